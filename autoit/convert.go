@@ -0,0 +1,246 @@
+package autoit
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+//dateFormat is the layout dates are exchanged with scripts in, as returned by FileGetTime
+const dateFormat = "20060102150405"
+
+var (
+	tokenType = reflect.TypeOf((*Token)(nil))
+	timeType  = reflect.TypeOf(time.Time{})
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+//fromGo converts a Go value to a token, storing slices and maps as arrays and maps and anything else as a host object
+func (vm *AutoItVM) fromGo(value reflect.Value) *Token {
+	if !value.IsValid() {
+		return NewToken(tNULL, "Null")
+	}
+	if value.Type() == tokenType {
+		if value.IsNil() {
+			return NewToken(tNULL, "Null")
+		}
+		return value.Interface().(*Token)
+	}
+	if value.Type() == timeType {
+		return NewToken(tSTRING, value.Interface().(time.Time).Format(dateFormat))
+	}
+
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		if value.IsNil() {
+			return NewToken(tNULL, "Null")
+		}
+	}
+	if value.CanInterface() {
+		if object, ok := value.Interface().(Object); ok {
+			return vm.AddHandle(object)
+		}
+	}
+
+	switch value.Kind() {
+	case reflect.Interface:
+		return vm.fromGo(value.Elem())
+	case reflect.Bool:
+		return NewToken(tBOOLEAN, value.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return NewToken(tNUMBER, value.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return NewToken(tNUMBER, fmt.Sprintf("%d", value.Uint()))
+	case reflect.Float32, reflect.Float64:
+		return NewDouble(value.Float())
+	case reflect.String:
+		return NewToken(tSTRING, value.String())
+	case reflect.Slice, reflect.Array:
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			data := make([]byte, value.Len())
+			reflect.Copy(reflect.ValueOf(data), value)
+			return NewToken(tBINARY, data)
+		}
+		values := make([]*Token, value.Len())
+		for i := range values {
+			values[i] = vm.fromGo(value.Index(i))
+		}
+		return vm.NewArray(values)
+	case reflect.Map:
+		values := make(map[string]*Token)
+		iter := value.MapRange()
+		for iter.Next() {
+			values[fmt.Sprint(iter.Key().Interface())] = vm.fromGo(iter.Value())
+		}
+		return vm.AddHandle(values)
+	}
+	if value.Kind() == reflect.Struct && value.CanAddr() {
+		//Fields of host objects are wrapped in place so setting their properties reaches the original
+		value = value.Addr()
+	}
+	if value.Kind() == reflect.Ptr {
+		return vm.addHostPointer(value)
+	}
+	return vm.AddHandle(NewHostObject(value.Interface()))
+}
+
+//hostKey identifies a Go pointer wrapped as a host object, with its type telling apart a struct and its first field
+type hostKey struct {
+	pointer uintptr
+	goType  reflect.Type
+}
+
+//addHostPointer wraps a Go pointer as a host object, returning the same handle every time the same pointer is wrapped
+func (vm *AutoItVM) addHostPointer(value reflect.Value) *Token {
+	key := hostKey{pointer: value.Pointer(), goType: value.Type()}
+	if handleId, exists := vm.hostHandles[key]; exists && vm.GetHandle(handleId) != nil {
+		return NewToken(tHANDLE, handleId)
+	}
+	handle := vm.AddHandle(NewHostObject(value.Interface()))
+	vm.hostHandles[key] = handle.Handle()
+	return handle
+}
+
+//toGo converts a token to a value of the given Go type
+func (vm *AutoItVM) toGo(t *Token, goType reflect.Type) (reflect.Value, error) {
+	if t == nil {
+		//Elements of arrays that were never assigned are empty
+		t = NewToken(tSTRING, "")
+	}
+	if goType == tokenType {
+		return reflect.ValueOf(t), nil
+	}
+	if goType == timeType {
+		for _, layout := range []string{dateFormat, "2006/01/02 15:04:05", "2006/01/02"} {
+			if date, err := time.ParseInLocation(layout, t.String(), time.Local); err == nil {
+				return reflect.ValueOf(date), nil
+			}
+		}
+		return reflect.Value{}, fmt.Errorf("%s is not a valid date", t.String())
+	}
+	if t.Type == tNULL {
+		return reflect.Zero(goType), nil
+	}
+
+	//Host objects hand back the Go value they wrap
+	if t.Type == tHANDLE {
+		switch handle := vm.GetHandle(t.Handle()).(type) {
+		case *hostObject:
+			if handle.value.Type().AssignableTo(goType) {
+				return handle.value, nil
+			}
+			if handle.value.Kind() == reflect.Ptr && handle.value.Elem().Type().AssignableTo(goType) {
+				return handle.value.Elem(), nil
+			}
+		case Object:
+			if reflect.TypeOf(handle).AssignableTo(goType) {
+				return reflect.ValueOf(handle), nil
+			}
+		}
+	}
+
+	value := reflect.New(goType).Elem()
+	switch goType.Kind() {
+	case reflect.Interface:
+		if goType.NumMethod() > 0 {
+			break
+		}
+		natural := vm.goValue(t)
+		if natural == nil {
+			return value, nil
+		}
+		value.Set(reflect.ValueOf(natural))
+		return value, nil
+	case reflect.Bool:
+		value.SetBool(t.Bool())
+		return value, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value.SetInt(intValue(t))
+		return value, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		value.SetUint(uint64(intValue(t)))
+		return value, nil
+	case reflect.Float32, reflect.Float64:
+		value.SetFloat(t.Float64())
+		return value, nil
+	case reflect.String:
+		value.SetString(t.String())
+		return value, nil
+	case reflect.Slice:
+		if goType.Elem().Kind() == reflect.Uint8 && t.Type != tHANDLE {
+			value.SetBytes(t.Bytes())
+			return value, nil
+		}
+		values := vm.GetArray(t)
+		if values == nil {
+			break
+		}
+		value.Set(reflect.MakeSlice(goType, len(values), len(values)))
+		for i, element := range values {
+			converted, err := vm.toGo(element, goType.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			value.Index(i).Set(converted)
+		}
+		return value, nil
+	case reflect.Map:
+		values, ok := vm.GetHandle(t.Handle()).(map[string]*Token)
+		if !ok || goType.Key().Kind() != reflect.String {
+			break
+		}
+		value.Set(reflect.MakeMapWithSize(goType, len(values)))
+		for key, element := range values {
+			converted, err := vm.toGo(element, goType.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			value.SetMapIndex(reflect.ValueOf(key).Convert(goType.Key()), converted)
+		}
+		return value, nil
+	}
+	return reflect.Value{}, fmt.Errorf("can't convert %s to %s", vm.varType(t), goType)
+}
+
+//goValue converts a token to the Go value that represents it best
+func (vm *AutoItVM) goValue(t *Token) interface{} {
+	if t == nil {
+		return ""
+	}
+	switch t.Type {
+	case tNULL:
+		return nil
+	case tBOOLEAN:
+		return t.Bool()
+	case tNUMBER, tDOUBLE:
+		number, integer, isInt := parseNumber(t.Data)
+		if isInt {
+			return integer
+		}
+		return number
+	case tBINARY:
+		return t.Bytes()
+	case tPTR, tHWND:
+		return uintptr(t.Uint())
+	case tHANDLE:
+		switch handle := vm.GetHandle(t.Handle()).(type) {
+		case []*Token:
+			values := make([]interface{}, len(handle))
+			for i, element := range handle {
+				values[i] = vm.goValue(element)
+			}
+			return values
+		case map[string]*Token:
+			values := make(map[string]interface{}, len(handle))
+			for key, element := range handle {
+				values[key] = vm.goValue(element)
+			}
+			return values
+		case *hostObject:
+			return handle.value.Interface()
+		default:
+			return handle
+		}
+	}
+	return t.String()
+}
@@ -0,0 +1,32 @@
+//go:build !linux && !darwin && !windows
+// +build !linux,!darwin,!windows
+
+package autoit
+
+import (
+	"errors"
+	"reflect"
+)
+
+//errDllUnsupported is returned when native libraries can't be used on this platform
+var errDllUnsupported = errors.New("native libraries are not supported on this platform")
+
+//openLibrary loads a shared library, searching the same paths as the dynamic linker
+func openLibrary(name string) (uintptr, error) {
+	return 0, errDllUnsupported
+}
+
+//librarySymbol returns the address of a function exported by a library
+func librarySymbol(library uintptr, name string) (uintptr, error) {
+	return 0, errDllUnsupported
+}
+
+//closeLibrary unloads a library
+func closeLibrary(library uintptr) error {
+	return errDllUnsupported
+}
+
+//callFunction calls a native function with the signature described by fnType
+func callFunction(address uintptr, fnType reflect.Type, args []reflect.Value) ([]reflect.Value, error) {
+	return nil, errDllUnsupported
+}
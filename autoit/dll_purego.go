@@ -0,0 +1,25 @@
+//go:build linux || darwin || windows
+// +build linux darwin windows
+
+package autoit
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/ebitengine/purego"
+)
+
+//callFunction calls a native function with the signature described by fnType
+func callFunction(address uintptr, fnType reflect.Type, args []reflect.Value) (results []reflect.Value, err error) {
+	//RegisterFunc panics on signatures it can't call on this platform
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("dllcall: %v", r)
+		}
+	}()
+
+	fn := reflect.New(fnType)
+	purego.RegisterFunc(fn.Interface(), address)
+	return fn.Elem().Call(args), nil
+}
@@ -0,0 +1,23 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package autoit
+
+import (
+	"github.com/ebitengine/purego"
+)
+
+//openLibrary loads a shared library, searching the same paths as the dynamic linker
+func openLibrary(name string) (uintptr, error) {
+	return purego.Dlopen(name, purego.RTLD_NOW|purego.RTLD_GLOBAL)
+}
+
+//librarySymbol returns the address of a function exported by a library
+func librarySymbol(library uintptr, name string) (uintptr, error) {
+	return purego.Dlsym(library, name)
+}
+
+//closeLibrary unloads a library
+func closeLibrary(library uintptr) error {
+	return purego.Dlclose(library)
+}
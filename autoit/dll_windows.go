@@ -0,0 +1,21 @@
+package autoit
+
+import (
+	"syscall"
+)
+
+//openLibrary loads a DLL, searching the same paths as LoadLibrary
+func openLibrary(name string) (uintptr, error) {
+	library, err := syscall.LoadLibrary(name)
+	return uintptr(library), err
+}
+
+//librarySymbol returns the address of a function exported by a library
+func librarySymbol(library uintptr, name string) (uintptr, error) {
+	return syscall.GetProcAddress(syscall.Handle(library), name)
+}
+
+//closeLibrary unloads a library
+func closeLibrary(library uintptr) error {
+	return syscall.FreeLibrary(syscall.Handle(library))
+}
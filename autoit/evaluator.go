@@ -80,7 +80,7 @@ func (e *Evaluator) mergeValue(tSource *Token) (*Token, error) {
 				tValue, tRead, err := NewEvaluator(e.vm, e.tokens[e.pos:]).Eval(true)
 				e.move(tRead)
 				if err != nil {
-					return nil, e.error("could not append value: %v %v", *tSource, err)
+					return nil, e.error("could not append value: %v %w", *tSource, err)
 				}
 				if tValue == nil {
 					return nil, e.error("could not append nil value")
@@ -88,13 +88,16 @@ func (e *Evaluator) mergeValue(tSource *Token) (*Token, error) {
 
 				tDest := NewToken(tSTRING, tSourceValue.String())
 				tDest.Data += tValue.String()
+				if err := e.vm.checkValue(tDest); err != nil {
+					return nil, err
+				}
 				e.vm.Log("append: %v", *tDest)
 				return e.mergeValue(tDest)
 			case "+":
 				tValue, tRead, err := NewEvaluator(e.vm, e.tokens[e.pos:]).Eval(true)
 				e.move(tRead)
 				if err != nil {
-					return nil, e.error("no value to sum: %v", err)
+					return nil, e.error("no value to sum: %w", err)
 				}
 
 				tDest := NewToken(tDOUBLE, tSourceValue.Float64() + tValue.Float64())
@@ -104,7 +107,7 @@ func (e *Evaluator) mergeValue(tSource *Token) (*Token, error) {
 				tValue, tRead, err := NewEvaluator(e.vm, e.tokens[e.pos:]).Eval(true)
 				e.move(tRead)
 				if err != nil {
-					return nil, e.error("no value to subtract: %v", err)
+					return nil, e.error("no value to subtract: %w", err)
 				}
 
 				tDest := NewToken(tDOUBLE, tSourceValue.Float64() - tValue.Float64())
@@ -114,7 +117,7 @@ func (e *Evaluator) mergeValue(tSource *Token) (*Token, error) {
 				tValue, tRead, err := NewEvaluator(e.vm, e.tokens[e.pos:]).Eval(true)
 				e.move(tRead)
 				if err != nil {
-					return nil, e.error("no value to multiply: %v", err)
+					return nil, e.error("no value to multiply: %w", err)
 				}
 
 				tDest := NewToken(tDOUBLE, tSourceValue.Float64() * tValue.Float64())
@@ -124,7 +127,7 @@ func (e *Evaluator) mergeValue(tSource *Token) (*Token, error) {
 				tValue, tRead, err := NewEvaluator(e.vm, e.tokens[e.pos:]).Eval(true)
 				e.move(tRead)
 				if err != nil {
-					return nil, e.error("no value to divide: %v", err)
+					return nil, e.error("no value to divide: %w", err)
 				}
 
 				tDest := NewToken(tDOUBLE, tSourceValue.Float64() / tValue.Float64())
@@ -134,7 +137,7 @@ func (e *Evaluator) mergeValue(tSource *Token) (*Token, error) {
 				tValue, tRead, err := NewEvaluator(e.vm, e.tokens[e.pos:]).Eval(true)
 				e.move(tRead)
 				if err != nil {
-					return nil, e.error("no value to compare less than: %v", err)
+					return nil, e.error("no value to compare less than: %w", err)
 				}
 
 				tDest := NewToken(tBOOLEAN, tSourceValue.Float64() < tValue.Float64())
@@ -144,7 +147,7 @@ func (e *Evaluator) mergeValue(tSource *Token) (*Token, error) {
 				tValue, tRead, err := NewEvaluator(e.vm, e.tokens[e.pos:]).Eval(true)
 				e.move(tRead)
 				if err != nil {
-					return nil, e.error("no value to compare greater than: %v", err)
+					return nil, e.error("no value to compare greater than: %w", err)
 				}
 
 				tDest := NewToken(tBOOLEAN, tSourceValue.Float64() > tValue.Float64())
@@ -154,7 +157,7 @@ func (e *Evaluator) mergeValue(tSource *Token) (*Token, error) {
 				tValue, tRead, err := NewEvaluator(e.vm, e.tokens[e.pos:]).Eval(true)
 				e.move(tRead)
 				if err != nil {
-					return nil, e.error("no value to compare equals: %v", err)
+					return nil, e.error("no value to compare equals: %w", err)
 				}
 
 				tDest := NewToken(tBOOLEAN, tSourceValue.String() == tValue.String())
@@ -168,7 +171,7 @@ func (e *Evaluator) mergeValue(tSource *Token) (*Token, error) {
 			tValue, tRead, err := NewEvaluator(e.vm, e.tokens[e.pos:]).Eval(true)
 			e.move(tRead)
 			if err != nil {
-				return nil, e.error("no value to compare bool: %v", err)
+				return nil, e.error("no value to compare bool: %w", err)
 			}
 
 			tDest := NewToken(tBOOLEAN, tSourceValue.Bool() && tValue.Bool())
@@ -178,7 +181,7 @@ func (e *Evaluator) mergeValue(tSource *Token) (*Token, error) {
 			tValue, tRead, err := NewEvaluator(e.vm, e.tokens[e.pos:]).Eval(true)
 			e.move(tRead)
 			if err != nil {
-				return nil, e.error("no value to or bool: %v", err)
+				return nil, e.error("no value to or bool: %w", err)
 			}
 
 			tDest := NewToken(tBOOLEAN, tSourceValue.Bool() || tValue.Bool())
@@ -194,7 +197,17 @@ func (e *Evaluator) mergeValue(tSource *Token) (*Token, error) {
 				return nil, e.error("expected map key for accessing %v", *tSource)
 			}
 
-			return e.vm.MapGet(tSource.Handle(), mapTokens[0].String()), nil
+			tDest := e.vm.MapGet(tSource.Handle(), mapTokens[0].String())
+			if tDest == nil {
+				return nil, e.error("subscript %s out of range for accessing %v", mapTokens[0].String(), *tSource)
+			}
+			return e.mergeValue(tDest)
+		case tPROPERTY, tMETHOD:
+			tDest, err := e.vm.objectMember(tSourceValue, tOp)
+			if err != nil {
+				return nil, e.error("%w", err)
+			}
+			return e.mergeValue(tDest)
 		case tEOL, tRIGHTPAREN, tRIGHTBRACK, tSEPARATOR, tTHEN:
 			e.move(-1)
 			return tSource, nil
@@ -261,7 +274,7 @@ func (e *Evaluator) Eval(expectValue bool) (*Token, int, error) {
 			return nil, e.pos, e.error("unexpected value")
 		}
 		return tEval, e.pos, nil
-	case tSTRING, tNUMBER, tDOUBLE, tBOOLEAN, tNOT, tNULL, tBINARY, tMACRO, tHANDLE:
+	case tSTRING, tNUMBER, tDOUBLE, tBOOLEAN, tNOT, tNULL, tBINARY, tMACRO, tHANDLE, tPTR, tHWND:
 		if !expectValue {
 			return nil, e.pos, e.error("unexpected value")
 		}
@@ -648,7 +661,7 @@ func (e *Evaluator) Eval(expectValue bool) (*Token, int, error) {
 					tValue, tRead, err := NewEvaluator(e.vm, e.tokens[e.pos:]).Eval(true)
 					e.move(tRead)
 					if err != nil {
-						return nil, e.pos+tRead, e.error("no value for variable declaration of $%s: %v", tVariable.String(), err)
+						return nil, e.pos+tRead, e.error("no value for variable declaration of $%s: %w", tVariable.String(), err)
 					}
 
 					e.vm.SetVariable(tVariable.String(), tValue)
@@ -683,6 +696,9 @@ func (e *Evaluator) Eval(expectValue bool) (*Token, int, error) {
 				if len(mapTokens) == 1 {
 					tEndLine := e.readToken()
 					if tEndLine == nil || tEndLine.Type == tEOL {
+						if err := e.vm.checkArraySize(mapTokens[0].Int64()); err != nil {
+							return nil, e.pos, err
+						}
 						handle := e.vm.AddHandle(make([]*Token, mapTokens[0].Int64()))
 						e.vm.SetVariable(tVariable.String(), handle)
 
@@ -703,7 +719,7 @@ func (e *Evaluator) Eval(expectValue bool) (*Token, int, error) {
 							tValue, tRead, err := NewEvaluator(e.vm, e.tokens[e.pos:]).Eval(true)
 							e.move(tRead)
 							if err != nil {
-								return nil, e.pos+tRead, e.error("no value for variable declaration of $%s: %v", tVariable.String(), err)
+								return nil, e.pos+tRead, e.error("no value for variable declaration of $%s: %w", tVariable.String(), err)
 							}
 
 							//e.vm.SetVariable(tVariable.String(), tValue)
@@ -729,6 +745,13 @@ func (e *Evaluator) Eval(expectValue bool) (*Token, int, error) {
 				}
 
 				return nil, e.pos, e.error("unexpected comma after array count for $%s", tVariable.String())
+			case tPROPERTY, tMETHOD:
+				e.move(-1)
+				tObject := e.vm.GetVariable(tVariable.String())
+				if tObject == nil {
+					return nil, e.pos, e.error("undeclared global variable $%s", tVariable.String())
+				}
+				return nil, e.pos, e.objectStatement(tObject)
 			case tEOL, tRIGHTPAREN:
 				e.move(-1)
 				e.vm.SetVariable(tVariable.String(), NewToken(tSTRING, ""))
@@ -753,6 +776,9 @@ func (e *Evaluator) Eval(expectValue bool) (*Token, int, error) {
 		forErr := forCall.Run(e.vm)
 		if forErr != nil {
 			e.vm.Log("for call error: %v", forErr)
+			if stopsScript(forErr) {
+				return nil, e.pos, forErr
+			}
 		}
 		return nil, e.pos, nil
 	case tCALL, tUDF:
@@ -796,6 +822,42 @@ func (e *Evaluator) Eval(expectValue bool) (*Token, int, error) {
 
 	return nil, e.pos, e.error("reached end of eval attempts for token: %v", *tEval)
 }
+//objectStatement runs a statement made of object members, assigning to the last member or calling it
+func (e *Evaluator) objectStatement(tObject *Token) error {
+	for {
+		tMember := e.readToken()
+		tNext := e.readToken()
+		if tNext != nil && (tNext.Type == tPROPERTY || tNext.Type == tMETHOD) {
+			e.move(-1)
+			tValue, err := e.vm.objectMember(tObject, tMember)
+			if err != nil {
+				return e.error("%w", err)
+			}
+			tObject = tValue
+			continue
+		}
+
+		if tNext != nil && tNext.Type == tOP && tNext.String() == "=" {
+			tValue, tRead, err := NewEvaluator(e.vm, e.tokens[e.pos:]).Eval(true)
+			e.move(tRead)
+			if err != nil {
+				return e.error("no value to assign to object member: %w", err)
+			}
+			if err := e.vm.setObjectMember(tObject, tMember, tValue); err != nil {
+				return e.error("%w", err)
+			}
+			return nil
+		}
+
+		if tNext != nil {
+			e.move(-1)
+		}
+		if _, err := e.vm.callObjectMember(tObject, tMember); err != nil {
+			return e.error("%w", err)
+		}
+		return nil
+	}
+}
 func (e *Evaluator) move(direction int) {
 	e.pos += direction
 }
@@ -0,0 +1,23 @@
+package autoit
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+//fileCreationTime returns the closest thing Linux has to a creation time, the inode change time
+func fileCreationTime(stat os.FileInfo) time.Time {
+	if sys, ok := stat.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(int64(sys.Ctim.Sec), int64(sys.Ctim.Nsec))
+	}
+	return stat.ModTime()
+}
+
+//fileAccessTime returns the last access time of a file
+func fileAccessTime(stat os.FileInfo) time.Time {
+	if sys, ok := stat.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(int64(sys.Atim.Sec), int64(sys.Atim.Nsec))
+	}
+	return stat.ModTime()
+}
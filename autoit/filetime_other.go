@@ -0,0 +1,19 @@
+//go:build !linux && !windows
+// +build !linux,!windows
+
+package autoit
+
+import (
+	"os"
+	"time"
+)
+
+//fileCreationTime falls back to the modification time where creation times aren't known
+func fileCreationTime(stat os.FileInfo) time.Time {
+	return stat.ModTime()
+}
+
+//fileAccessTime falls back to the modification time where access times aren't known
+func fileAccessTime(stat os.FileInfo) time.Time {
+	return stat.ModTime()
+}
@@ -0,0 +1,23 @@
+package autoit
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+//fileCreationTime returns the creation time of a file
+func fileCreationTime(stat os.FileInfo) time.Time {
+	if sys, ok := stat.Sys().(*syscall.Win32FileAttributeData); ok {
+		return time.Unix(0, sys.CreationTime.Nanoseconds())
+	}
+	return stat.ModTime()
+}
+
+//fileAccessTime returns the last access time of a file
+func fileAccessTime(stat os.FileInfo) time.Time {
+	if sys, ok := stat.Sys().(*syscall.Win32FileAttributeData); ok {
+		return time.Unix(0, sys.LastAccessTime.Nanoseconds())
+	}
+	return stat.ModTime()
+}
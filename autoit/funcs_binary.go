@@ -0,0 +1,393 @@
+package autoit
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+//String encodings used by StringToBinary and BinaryToString
+const (
+	encodingANSI    = 1
+	encodingUTF16LE = 2
+	encodingUTF16BE = 3
+	encodingUTF8    = 4
+)
+
+//ansiHigh holds the Windows-1252 code points for bytes 0x80-0x9F, the rest of the code page matches Latin-1
+var ansiHigh = [32]rune{
+	0x20AC, 0x0081, 0x201A, 0x0192, 0x201E, 0x2026, 0x2020, 0x2021, 0x02C6, 0x2030, 0x0160, 0x2039, 0x0152, 0x008D, 0x017D, 0x008F,
+	0x0090, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014, 0x02DC, 0x2122, 0x0161, 0x203A, 0x0153, 0x009D, 0x017E, 0x0178,
+}
+
+func init() {
+	//Binary management
+	stdFunctions["binarylen"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "binary"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			return NewToken(tNUMBER, len(binaryData(args["binary"]))), nil
+		},
+	}
+	stdFunctions["binarymid"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "binary"},
+			&FunctionArg{Name: "start"},
+			&FunctionArg{Name: "count", DefaultValue: NewToken(tNUMBER, -1)},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			data := binaryData(args["binary"])
+			start := args["start"].Int()
+			if start < 1 || start > len(data) {
+				vm.SetError(1)
+				return NewToken(tBINARY, []byte{}), nil
+			}
+			end := len(data)
+			if count := args["count"].Int(); count >= 0 && start-1+count < end {
+				end = start - 1 + count
+			}
+			return NewToken(tBINARY, data[start-1:end]), nil
+		},
+	}
+	stdFunctions["binarytostring"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "binary"},
+			&FunctionArg{Name: "flag", DefaultValue: NewToken(tNUMBER, encodingANSI)},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			flag := args["flag"].Int()
+			if flag < encodingANSI || flag > encodingUTF8 {
+				vm.SetError(2)
+				return NewToken(tSTRING, ""), nil
+			}
+			return NewToken(tSTRING, decodeString(binaryData(args["binary"]), flag)), nil
+		},
+	}
+	stdFunctions["stringtobinary"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "expression"},
+			&FunctionArg{Name: "flag", DefaultValue: NewToken(tNUMBER, encodingANSI)},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			flag := args["flag"].Int()
+			if flag < encodingANSI || flag > encodingUTF8 {
+				vm.SetError(2)
+				return NewToken(tBINARY, []byte{}), nil
+			}
+			return NewToken(tBINARY, encodeString(args["expression"].String(), flag)), nil
+		},
+	}
+
+	//Hexadecimal conversions
+	stdFunctions["hex"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "expression"},
+			&FunctionArg{Name: "length", DefaultValue: NewToken(tDEFAULT, "")},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			expression := args["expression"]
+			if expression.Type == tBINARY {
+				return NewToken(tSTRING, strings.ToUpper(expression.Data)), nil
+			}
+
+			digits := ""
+			if number := expression.Float64(); (expression.Type == tNUMBER || expression.Type == tDOUBLE) && !isWholeNumber(number) {
+				digits = fmt.Sprintf("%016X", math.Float64bits(number))
+			} else if value := intValue(expression); isInt32(value) {
+				digits = fmt.Sprintf("%08X", uint32(value))
+			} else {
+				digits = fmt.Sprintf("%016X", uint64(value))
+			}
+
+			if args["length"].Type != tDEFAULT {
+				length := args["length"].Int()
+				if length < 1 {
+					vm.SetError(1)
+					return NewToken(tSTRING, ""), nil
+				}
+				if length < len(digits) {
+					digits = digits[len(digits)-length:]
+				} else {
+					digits = strings.Repeat("0", length-len(digits)) + digits
+				}
+			}
+			return NewToken(tSTRING, digits), nil
+		},
+	}
+	stdFunctions["dec"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "hex"},
+			&FunctionArg{Name: "flag", DefaultValue: NewToken(tNUMBER, 0)},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			digits := args["hex"].String()
+			if args["hex"].Type == tBINARY {
+				digits = args["hex"].Data
+			}
+			number, err := strconv.ParseUint(digits, 16, 64)
+			if err != nil || len(digits) > 16 {
+				vm.SetError(1)
+				return NewToken(tNUMBER, 0), nil
+			}
+
+			switch args["flag"].Int() {
+			case 1: //32-bit integer
+				return NewToken(tNUMBER, int64(int32(number))), nil
+			case 2: //64-bit integer
+				return NewToken(tNUMBER, int64(number)), nil
+			case 3: //Double
+				return NewToken(tDOUBLE, math.Float64frombits(number)), nil
+			}
+			if len(digits) <= 8 {
+				return NewToken(tNUMBER, int64(int32(number))), nil
+			}
+			return NewToken(tNUMBER, int64(number)), nil
+		},
+	}
+
+	//Character codes
+	stdFunctions["asc"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "char"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			char := args["char"].String()
+			if char == "" {
+				return NewToken(tNUMBER, 0), nil
+			}
+			r, _ := utf8.DecodeRuneInString(char)
+			return NewToken(tNUMBER, int(ansiByte(r))), nil
+		},
+	}
+	stdFunctions["ascw"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "char"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			char := args["char"].String()
+			if char == "" {
+				return NewToken(tNUMBER, 0), nil
+			}
+			r, _ := utf8.DecodeRuneInString(char)
+			return NewToken(tNUMBER, int(r)), nil
+		},
+	}
+	stdFunctions["chr"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "ascii"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			code := intValue(args["ascii"])
+			if code < 0 || code > 255 {
+				vm.SetError(1)
+				return NewToken(tSTRING, ""), nil
+			}
+			return NewToken(tSTRING, decodeString([]byte{byte(code)}, encodingANSI)), nil
+		},
+	}
+	stdFunctions["chrw"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "unicode"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			code := intValue(args["unicode"])
+			if code < 0 || code > 65535 {
+				vm.SetError(1)
+				return NewToken(tSTRING, ""), nil
+			}
+			return NewToken(tSTRING, string(rune(code))), nil
+		},
+	}
+	stdFunctions["stringtoasciiarray"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "string"},
+			&FunctionArg{Name: "start", DefaultValue: NewToken(tNUMBER, 0)},
+			&FunctionArg{Name: "end", DefaultValue: NewToken(tNUMBER, -1)},
+			&FunctionArg{Name: "encoding", DefaultValue: NewToken(tNUMBER, 0)},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			codes := make([]int, 0)
+			switch args["encoding"].Int() {
+			case 0: //UTF-16
+				for _, code := range utf16.Encode([]rune(args["string"].String())) {
+					codes = append(codes, int(code))
+				}
+			case 1: //ANSI
+				for _, code := range encodeString(args["string"].String(), encodingANSI) {
+					codes = append(codes, int(code))
+				}
+			case 2: //UTF-8
+				for _, code := range []byte(args["string"].String()) {
+					codes = append(codes, int(code))
+				}
+			default:
+				vm.SetError(1)
+				return NewToken(tSTRING, ""), nil
+			}
+
+			start := args["start"].Int()
+			end := args["end"].Int()
+			if end < 0 || end > len(codes) {
+				end = len(codes)
+			}
+			if start < 0 || start > end {
+				start = end
+			}
+
+			values := make([]*Token, 0)
+			for _, code := range codes[start:end] {
+				values = append(values, NewToken(tNUMBER, code))
+			}
+			return vm.NewArray(values), nil
+		},
+	}
+	stdFunctions["stringfromasciiarray"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "array"},
+			&FunctionArg{Name: "start", DefaultValue: NewToken(tNUMBER, 0)},
+			&FunctionArg{Name: "end", DefaultValue: NewToken(tNUMBER, -1)},
+			&FunctionArg{Name: "encoding", DefaultValue: NewToken(tNUMBER, 0)},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			values := vm.GetArray(args["array"])
+			if values == nil {
+				vm.SetError(1)
+				return NewToken(tSTRING, ""), nil
+			}
+
+			start := args["start"].Int()
+			end := args["end"].Int()
+			if end < 0 || end > len(values) {
+				end = len(values)
+			}
+			if start < 0 || start > end {
+				vm.SetError(2)
+				return NewToken(tSTRING, ""), nil
+			}
+
+			codes := make([]int64, 0)
+			for _, value := range values[start:end] {
+				if value == nil {
+					codes = append(codes, 0)
+					continue
+				}
+				codes = append(codes, intValue(value))
+			}
+
+			switch args["encoding"].Int() {
+			case 0: //UTF-16
+				units := make([]uint16, 0)
+				for _, code := range codes {
+					units = append(units, uint16(code))
+				}
+				return NewToken(tSTRING, string(utf16.Decode(units))), nil
+			case 1, 2: //ANSI, UTF-8
+				data := make([]byte, 0)
+				for _, code := range codes {
+					data = append(data, byte(code))
+				}
+				if args["encoding"].Int() == 1 {
+					return NewToken(tSTRING, decodeString(data, encodingANSI)), nil
+				}
+				return NewToken(tSTRING, string(data)), nil
+			}
+			vm.SetError(3)
+			return NewToken(tSTRING, ""), nil
+		},
+	}
+}
+
+//encodeString converts a string into bytes using the given encoding
+func encodeString(str string, encoding int) []byte {
+	switch encoding {
+	case encodingUTF16LE, encodingUTF16BE:
+		units := utf16.Encode([]rune(str))
+		data := make([]byte, len(units)*2)
+		for i, unit := range units {
+			if encoding == encodingUTF16LE {
+				binary.LittleEndian.PutUint16(data[i*2:], unit)
+			} else {
+				binary.BigEndian.PutUint16(data[i*2:], unit)
+			}
+		}
+		return data
+	case encodingUTF8:
+		return []byte(str)
+	}
+
+	data := make([]byte, 0, len(str))
+	for _, r := range str {
+		data = append(data, ansiByte(r))
+	}
+	return data
+}
+
+//decodeString converts bytes in the given encoding into a string
+func decodeString(data []byte, encoding int) string {
+	switch encoding {
+	case encodingUTF16LE, encodingUTF16BE:
+		units := make([]uint16, len(data)/2)
+		for i := range units {
+			if encoding == encodingUTF16LE {
+				units[i] = binary.LittleEndian.Uint16(data[i*2:])
+			} else {
+				units[i] = binary.BigEndian.Uint16(data[i*2:])
+			}
+		}
+		return string(utf16.Decode(units))
+	case encodingUTF8:
+		return string(data)
+	}
+
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		if b >= 0x80 && b <= 0x9F {
+			runes[i] = ansiHigh[b-0x80]
+		} else {
+			runes[i] = rune(b)
+		}
+	}
+	return string(runes)
+}
+
+//ansiByte returns the Windows-1252 byte for a rune, or '?' if it can't be represented
+func ansiByte(r rune) byte {
+	if r < 0x80 || (r >= 0xA0 && r <= 0xFF) {
+		return byte(r)
+	}
+	for i, high := range ansiHigh {
+		if high == r {
+			return byte(0x80 + i)
+		}
+	}
+	return '?'
+}
+
+//binaryData returns the data of a binary argument, converting strings the way Binary does
+func binaryData(t *Token) []byte {
+	if t.Type != tSTRING {
+		return t.Bytes()
+	}
+	if data, ok := hexBytes(t.String()); ok {
+		return data
+	}
+	return encodeString(t.String(), encodingANSI)
+}
+
+//hexBytes decodes a "0x" prefixed hex string, returning false if it isn't one
+func hexBytes(str string) ([]byte, bool) {
+	if len(str) < 2 || (str[:2] != "0x" && str[:2] != "0X") {
+		return nil, false
+	}
+	data, err := hex.DecodeString(str[2:])
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
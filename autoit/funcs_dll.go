@@ -0,0 +1,340 @@
+package autoit
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+//dllCallMaxParams is the number of type and parameter pairs DllCall accepts
+const dllCallMaxParams = 30
+
+//dllStringChars is the minimum number of characters allocated for str and wstr parameters, so they can be used as buffers
+const dllStringChars = 65536
+
+//dllLibrary is a library opened by DllOpen
+type dllLibrary struct {
+	handle uintptr
+}
+
+//dllCallTypes holds the Go type each DllCall type is passed as
+var dllCallTypes = map[string]reflect.Type{
+	"byte":      reflect.TypeOf(uint8(0)),
+	"boolean":   reflect.TypeOf(uint8(0)),
+	"short":     reflect.TypeOf(int16(0)),
+	"ushort":    reflect.TypeOf(uint16(0)),
+	"word":      reflect.TypeOf(uint16(0)),
+	"int":       reflect.TypeOf(int32(0)),
+	"long":      reflect.TypeOf(int32(0)),
+	"bool":      reflect.TypeOf(int32(0)),
+	"uint":      reflect.TypeOf(uint32(0)),
+	"ulong":     reflect.TypeOf(uint32(0)),
+	"dword":     reflect.TypeOf(uint32(0)),
+	"int64":     reflect.TypeOf(int64(0)),
+	"uint64":    reflect.TypeOf(uint64(0)),
+	"float":     reflect.TypeOf(float32(0)),
+	"double":    reflect.TypeOf(float64(0)),
+	"ptr":       reflect.TypeOf(uintptr(0)),
+	"hwnd":      reflect.TypeOf(uintptr(0)),
+	"handle":    reflect.TypeOf(uintptr(0)),
+	"int_ptr":   reflect.TypeOf(uintptr(0)),
+	"long_ptr":  reflect.TypeOf(uintptr(0)),
+	"lresult":   reflect.TypeOf(uintptr(0)),
+	"lparam":    reflect.TypeOf(uintptr(0)),
+	"uint_ptr":  reflect.TypeOf(uintptr(0)),
+	"ulong_ptr": reflect.TypeOf(uintptr(0)),
+	"dword_ptr": reflect.TypeOf(uintptr(0)),
+	"wparam":    reflect.TypeOf(uintptr(0)),
+	"str":       reflect.TypeOf(uintptr(0)),
+	"wstr":      reflect.TypeOf(uintptr(0)),
+}
+
+//dllParam is a parameter prepared for a native call
+type dllParam struct {
+	kind   string
+	byRef  bool
+	value  *Token
+	buffer *dllStruct //Memory passed by pointer, read back after the call
+}
+
+func init() {
+	//DLL calls
+	stdFunctions["dllopen"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "filename"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			handle, err := openLibrary(args["filename"].String())
+			if err != nil {
+				vm.SetError(1)
+				return NewToken(tNUMBER, -1), nil
+			}
+			return vm.AddHandle(&dllLibrary{handle: handle}), nil
+		},
+	}
+	stdFunctions["dllclose"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "handle"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			library, ok := vm.GetHandle(args["handle"].Handle()).(*dllLibrary)
+			if !ok {
+				return NewToken(tNUMBER, 0), nil
+			}
+			closeLibrary(library.handle)
+			vm.DestroyHandle(args["handle"].Handle())
+			return NewToken(tNUMBER, 1), nil
+		},
+	}
+
+	dllCallArgs := []*FunctionArg{
+		&FunctionArg{Name: "dll"},
+		&FunctionArg{Name: "returntype"},
+		&FunctionArg{Name: "function"},
+	}
+	for i := 1; i <= dllCallMaxParams; i++ {
+		dllCallArgs = append(dllCallArgs,
+			&FunctionArg{Name: fmt.Sprintf("type%d", i), DefaultValue: NewToken(tDEFAULT, "")},
+			&FunctionArg{Name: fmt.Sprintf("param%d", i), DefaultValue: NewToken(tDEFAULT, "")},
+		)
+	}
+	stdFunctions["dllcall"] = &Function{
+		Args: dllCallArgs,
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			//Libraries are opened for the call when given by name
+			var library *dllLibrary
+			if args["dll"].Type == tHANDLE {
+				library, _ = vm.GetHandle(args["dll"].Handle()).(*dllLibrary)
+			}
+			if library == nil {
+				handle, err := openLibrary(args["dll"].String())
+				if err != nil {
+					vm.SetError(1)
+					return NewToken(tNUMBER, 0), nil
+				}
+				defer closeLibrary(handle)
+				library = &dllLibrary{handle: handle}
+			}
+
+			//The calling convention only matters on 32-bit Windows, where purego always uses stdcall
+			returnKind := strings.ToLower(strings.TrimSpace(strings.SplitN(args["returntype"].String(), ":", 2)[0]))
+			var returnType reflect.Type
+			if returnKind != "none" {
+				var ok bool
+				returnType, ok = dllCallTypes[returnKind]
+				if !ok {
+					vm.SetError(2)
+					return NewToken(tNUMBER, 0), nil
+				}
+			}
+
+			address, err := librarySymbol(library.handle, args["function"].String())
+			if err != nil {
+				vm.SetError(3)
+				return NewToken(tNUMBER, 0), nil
+			}
+
+			params := make([]*dllParam, 0)
+			for i := 1; i <= dllCallMaxParams; i++ {
+				paramType := args[fmt.Sprintf("type%d", i)]
+				if paramType.Type == tDEFAULT {
+					break
+				}
+				value := args[fmt.Sprintf("param%d", i)]
+				if value.Type == tDEFAULT {
+					vm.SetError(4)
+					return NewToken(tNUMBER, 0), nil
+				}
+				param, err := vm.dllParam(paramType.String(), value)
+				if err != nil {
+					vm.SetError(5)
+					vm.SetExtended(i)
+					return NewToken(tNUMBER, 0), nil
+				}
+				params = append(params, param)
+			}
+
+			inTypes := make([]reflect.Type, len(params))
+			inValues := make([]reflect.Value, len(params))
+			for i, param := range params {
+				inValues[i] = param.reflectValue()
+				inTypes[i] = inValues[i].Type()
+			}
+			outTypes := make([]reflect.Type, 0)
+			if returnType != nil {
+				outTypes = append(outTypes, returnType)
+			}
+
+			results, err := callFunction(address, reflect.FuncOf(inTypes, outTypes, false), inValues)
+			runtime.KeepAlive(params)
+			if err != nil {
+				vm.SetError(1)
+				return NewToken(tNUMBER, 0), nil
+			}
+
+			values := []*Token{NewToken(tSTRING, "")}
+			if returnType != nil {
+				values[0] = dllReturnValue(returnKind, results[0])
+			}
+			for _, param := range params {
+				values = append(values, param.result())
+			}
+			return vm.NewArray(values), nil
+		},
+	}
+}
+
+//dllParam prepares a value to be passed to a native function as the given type
+func (vm *AutoItVM) dllParam(paramType string, value *Token) (*dllParam, error) {
+	kind := strings.ToLower(strings.TrimSpace(paramType))
+	param := &dllParam{value: value}
+	if strings.HasSuffix(kind, "*") {
+		param.byRef = true
+		kind = strings.TrimSpace(strings.TrimSuffix(kind, "*"))
+	}
+	param.kind = kind
+
+	switch kind {
+	case "struct":
+		//Structs are only passed by pointer
+		structure, ok := vm.GetHandle(value.Handle()).(*dllStruct)
+		if !ok || !param.byRef || value.Type != tHANDLE {
+			return nil, fmt.Errorf("dllcall: %s is not a struct", value.String())
+		}
+		param.buffer = structure
+		return param, nil
+	case "str", "wstr":
+		width, encoding := 1, encodingANSI
+		if kind == "wstr" {
+			width, encoding = 2, encodingUTF16LE
+		}
+		data := encodeString(value.String(), encoding)
+		size := len(data) + width
+		if size < dllStringChars*width {
+			size = dllStringChars * width
+		}
+		param.buffer = &dllStruct{data: make([]byte, size)}
+		copy(param.buffer.data, data)
+		return param, nil
+	}
+
+	if _, ok := dllCallTypes[kind]; !ok {
+		return nil, fmt.Errorf("dllcall: unknown type %s", paramType)
+	}
+	if param.byRef {
+		structure, element, err := dllScalar(kind)
+		if err != nil {
+			return nil, err
+		}
+		structure.set(element, 0, vm.dllPointerValue(value))
+		param.buffer = structure
+		return param, nil
+	}
+	param.value = vm.dllPointerValue(value)
+	return param, nil
+}
+
+//dllPointerValue replaces structs with their pointers, so they can be passed as pointer types
+func (vm *AutoItVM) dllPointerValue(value *Token) *Token {
+	if value.Type == tHANDLE {
+		if structure, ok := vm.GetHandle(value.Handle()).(*dllStruct); ok {
+			return NewToken(tPTR, fmt.Sprintf("%d", uint64(structure.pointer())))
+		}
+	}
+	return value
+}
+
+//reflectValue returns the value passed to the native function
+func (p *dllParam) reflectValue() reflect.Value {
+	if p.buffer != nil {
+		return reflect.ValueOf(p.buffer.pointer())
+	}
+
+	value := reflect.New(dllCallTypes[p.kind]).Elem()
+	switch value.Kind() {
+	case reflect.Float32, reflect.Float64:
+		value.SetFloat(p.value.Float64())
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value.SetInt(intValue(p.value))
+	default:
+		value.SetUint(uint64(intValue(p.value)))
+	}
+	return value
+}
+
+//result returns the value of the parameter after the call, reading back anything the function could have changed
+func (p *dllParam) result() *Token {
+	switch p.kind {
+	case "struct":
+		return p.value
+	case "str":
+		return NewToken(tSTRING, decodeString(cString(p.buffer.data, 1), encodingANSI))
+	case "wstr":
+		return NewToken(tSTRING, decodeString(cString(p.buffer.data, 2), encodingUTF16LE))
+	}
+	if p.byRef {
+		return p.buffer.get(p.buffer.elements[0], 0)
+	}
+	return p.value
+}
+
+//dllScalar returns a struct holding a single value of the given type, with room for any value to be written to it
+func dllScalar(kind string) (*dllStruct, *dllStructElement, error) {
+	element, err := parseDllStructElement(kind)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &dllStruct{data: make([]byte, 8), elements: []*dllStructElement{element}}, element, nil
+}
+
+//dllReturnValue converts the value returned by a native function
+func dllReturnValue(kind string, value reflect.Value) *Token {
+	switch kind {
+	case "str", "wstr":
+		width, encoding := 1, encodingANSI
+		if kind == "wstr" {
+			width, encoding = 2, encodingUTF16LE
+		}
+		address := uintptr(value.Uint())
+		if address == 0 {
+			return NewToken(tSTRING, "")
+		}
+		return NewToken(tSTRING, decodeString(readCString(address, width), encoding))
+	}
+
+	//The value is read back the same way a struct element would be
+	structure, element, _ := dllScalar(kind)
+	switch value.Kind() {
+	case reflect.Float32:
+		binary.LittleEndian.PutUint32(structure.data, math.Float32bits(float32(value.Float())))
+	case reflect.Float64:
+		binary.LittleEndian.PutUint64(structure.data, math.Float64bits(value.Float()))
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		binary.LittleEndian.PutUint64(structure.data, uint64(value.Int()))
+	default:
+		binary.LittleEndian.PutUint64(structure.data, value.Uint())
+	}
+	return structure.get(element, 0)
+}
+
+//readCString copies a NUL terminated string of characters of the given width from native memory
+func readCString(address uintptr, width int) []byte {
+	data := make([]byte, 0)
+	for {
+		char := pointerBytes(address+uintptr(len(data)), width)
+		terminated := true
+		for _, b := range char {
+			if b != 0 {
+				terminated = false
+				break
+			}
+		}
+		if terminated {
+			return data
+		}
+		data = append(data, char...)
+	}
+}
@@ -0,0 +1,511 @@
+package autoit
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+//ptrSize is the size of pointer sized types, which is 4 on 32-bit builds and 8 on 64-bit builds
+const ptrSize = int(unsafe.Sizeof(uintptr(0)))
+
+//defaultStructPacking matches the default packing of the Windows C compilers
+const defaultStructPacking = 8
+
+//dllStructTypes holds the size of every type usable in a struct definition
+var dllStructTypes = map[string]int{
+	"byte":      1,
+	"boolean":   1,
+	"char":      1,
+	"short":     2,
+	"ushort":    2,
+	"word":      2,
+	"wchar":     2,
+	"int":       4,
+	"long":      4,
+	"bool":      4,
+	"uint":      4,
+	"ulong":     4,
+	"dword":     4,
+	"float":     4,
+	"int64":     8,
+	"uint64":    8,
+	"double":    8,
+	"ptr":       ptrSize,
+	"hwnd":      ptrSize,
+	"handle":    ptrSize,
+	"int_ptr":   ptrSize,
+	"long_ptr":  ptrSize,
+	"lresult":   ptrSize,
+	"lparam":    ptrSize,
+	"uint_ptr":  ptrSize,
+	"ulong_ptr": ptrSize,
+	"dword_ptr": ptrSize,
+	"wparam":    ptrSize,
+}
+
+//dllStruct is a block of memory laid out by a struct definition
+type dllStruct struct {
+	data     []byte
+	elements []*dllStructElement
+}
+
+//dllStructElement is a single, possibly array, element of a struct
+type dllStructElement struct {
+	name   string
+	kind   string
+	offset int
+	size   int //Size of a single value
+	count  int
+}
+
+func init() {
+	//DLL structures
+	stdFunctions["dllstructcreate"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "struct"},
+			&FunctionArg{Name: "pointer", DefaultValue: NewToken(tDEFAULT, "")},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			elements, size, err := parseDllStruct(args["struct"].String())
+			if err != nil {
+				vm.SetError(err.(*dllStructError).code)
+				return NewToken(tNUMBER, 0), nil
+			}
+			if size == 0 {
+				vm.SetError(4)
+				return NewToken(tNUMBER, 0), nil
+			}
+
+			structure := &dllStruct{elements: elements}
+			if args["pointer"].Type == tDEFAULT {
+				structure.data = make([]byte, size)
+			} else {
+				address := uintptr(intValue(args["pointer"]))
+				if address == 0 {
+					vm.SetError(1)
+					return NewToken(tNUMBER, 0), nil
+				}
+				structure.data = pointerBytes(address, size)
+			}
+			return vm.AddHandle(structure), nil
+		},
+	}
+	stdFunctions["dllstructgetdata"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "struct"},
+			&FunctionArg{Name: "element"},
+			&FunctionArg{Name: "index", DefaultValue: NewToken(tDEFAULT, "")},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			structure, ok := vm.GetHandle(args["struct"].Handle()).(*dllStruct)
+			if !ok {
+				vm.SetError(1)
+				return NewToken(tNUMBER, 0), nil
+			}
+			element := structure.element(args["element"])
+			if element == nil {
+				vm.SetError(2)
+				return NewToken(tNUMBER, 0), nil
+			}
+
+			if args["index"].Type == tDEFAULT {
+				return structure.getAll(element), nil
+			}
+			index := args["index"].Int()
+			if index < 1 || index > element.count {
+				vm.SetError(3)
+				return NewToken(tNUMBER, 0), nil
+			}
+			return structure.get(element, index-1), nil
+		},
+	}
+	stdFunctions["dllstructsetdata"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "struct"},
+			&FunctionArg{Name: "element"},
+			&FunctionArg{Name: "value"},
+			&FunctionArg{Name: "index", DefaultValue: NewToken(tDEFAULT, "")},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			structure, ok := vm.GetHandle(args["struct"].Handle()).(*dllStruct)
+			if !ok {
+				vm.SetError(1)
+				return NewToken(tNUMBER, 0), nil
+			}
+			element := structure.element(args["element"])
+			if element == nil {
+				vm.SetError(2)
+				return NewToken(tNUMBER, 0), nil
+			}
+
+			if args["index"].Type == tDEFAULT {
+				structure.setAll(element, args["value"])
+				return structure.getAll(element), nil
+			}
+			index := args["index"].Int()
+			if index < 1 || index > element.count {
+				vm.SetError(3)
+				return NewToken(tNUMBER, 0), nil
+			}
+			structure.set(element, index-1, args["value"])
+			return structure.get(element, index-1), nil
+		},
+	}
+	stdFunctions["dllstructgetsize"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "struct"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			structure, ok := vm.GetHandle(args["struct"].Handle()).(*dllStruct)
+			if !ok {
+				vm.SetError(1)
+				return NewToken(tNUMBER, 0), nil
+			}
+			return NewToken(tNUMBER, len(structure.data)), nil
+		},
+	}
+	stdFunctions["dllstructgetptr"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "struct"},
+			&FunctionArg{Name: "element", DefaultValue: NewToken(tDEFAULT, "")},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			structure, ok := vm.GetHandle(args["struct"].Handle()).(*dllStruct)
+			if !ok {
+				vm.SetError(1)
+				return NewToken(tNUMBER, 0), nil
+			}
+			offset := 0
+			if args["element"].Type != tDEFAULT {
+				element := structure.element(args["element"])
+				if element == nil {
+					vm.SetError(2)
+					return NewToken(tNUMBER, 0), nil
+				}
+				offset = element.offset
+			}
+			return NewToken(tPTR, fmt.Sprintf("%d", uint64(structure.pointer()+uintptr(offset)))), nil
+		},
+	}
+	stdFunctions["isdllstruct"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "variable"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			if args["variable"].Type != tHANDLE {
+				return NewToken(tNUMBER, 0), nil
+			}
+			_, ok := vm.GetHandle(args["variable"].Handle()).(*dllStruct)
+			return NewToken(tNUMBER, boolInt(ok)), nil
+		},
+	}
+}
+
+//dllStructError is returned when a struct definition can't be parsed, holding the @error value to set
+type dllStructError struct {
+	code int
+	msg  string
+}
+
+func (e *dllStructError) Error() string {
+	return "dllstruct: " + e.msg
+}
+
+//parseDllStruct lays out the elements of a struct definition, returning them with the size of the struct
+func parseDllStruct(definition string) ([]*dllStructElement, int, error) {
+	items := make([]string, 0)
+	for _, item := range strings.Split(definition, ";") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+
+	position := 0
+	elements, size, _, err := parseDllStructBlock(items, &position, defaultStructPacking, false)
+	if err != nil {
+		return nil, 0, err
+	}
+	return elements, size, nil
+}
+
+//parseDllStructBlock lays out items until the end of the definition or the endstruct closing a nested struct,
+//returning the elements with offsets relative to the block, the padded block size and the block alignment
+func parseDllStructBlock(items []string, position *int, packing int, nested bool) ([]*dllStructElement, int, int, error) {
+	elements := make([]*dllStructElement, 0)
+	offset, alignment := 0, 1
+
+	for *position < len(items) {
+		item := items[*position]
+		*position++
+		fields := strings.Fields(strings.ToLower(item))
+
+		switch fields[0] {
+		case "align":
+			packing = defaultStructPacking
+			if len(fields) > 1 {
+				value, err := strconv.Atoi(fields[1])
+				if err != nil || (value != 1 && value != 2 && value != 4 && value != 8 && value != 16) {
+					return nil, 0, 0, &dllStructError{5, "invalid alignment " + fields[1]}
+				}
+				packing = value
+			}
+			continue
+		case "struct":
+			block, blockSize, blockAlignment, err := parseDllStructBlock(items, position, packing, true)
+			if err != nil {
+				return nil, 0, 0, err
+			}
+			offset = alignOffset(offset, blockAlignment)
+			for _, element := range block {
+				element.offset += offset
+			}
+			elements = append(elements, block...)
+			offset += blockSize
+			if blockAlignment > alignment {
+				alignment = blockAlignment
+			}
+			continue
+		case "endstruct":
+			if !nested {
+				return nil, 0, 0, &dllStructError{5, "endstruct without struct"}
+			}
+			return elements, alignOffset(offset, alignment), alignment, nil
+		}
+
+		element, err := parseDllStructElement(item)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		elementAlignment := element.size
+		if elementAlignment > packing {
+			elementAlignment = packing
+		}
+		offset = alignOffset(offset, elementAlignment)
+		element.offset = offset
+		offset += element.size * element.count
+		if elementAlignment > alignment {
+			alignment = elementAlignment
+		}
+		elements = append(elements, element)
+	}
+
+	if nested {
+		return nil, 0, 0, &dllStructError{5, "struct without endstruct"}
+	}
+	return elements, alignOffset(offset, alignment), alignment, nil
+}
+
+//parseDllStructElement parses an element such as "int", "wchar[256]" or "dword name[4]"
+func parseDllStructElement(item string) (*dllStructElement, error) {
+	count := 1
+	if open := strings.Index(item, "["); open != -1 {
+		if !strings.HasSuffix(item, "]") {
+			return nil, &dllStructError{5, "malformed element " + item}
+		}
+		value, err := strconv.Atoi(strings.TrimSpace(item[open+1 : len(item)-1]))
+		if err != nil || value < 1 {
+			return nil, &dllStructError{5, "invalid element count in " + item}
+		}
+		count = value
+		item = item[:open]
+	}
+
+	fields := strings.Fields(item)
+	if len(fields) == 0 || len(fields) > 2 {
+		return nil, &dllStructError{5, "malformed element " + item}
+	}
+	kind := strings.ToLower(fields[0])
+	size, ok := dllStructTypes[kind]
+	if !ok {
+		return nil, &dllStructError{2, "unknown type " + fields[0]}
+	}
+
+	element := &dllStructElement{kind: kind, size: size, count: count}
+	if len(fields) == 2 {
+		element.name = fields[1]
+	}
+	return element, nil
+}
+
+//alignOffset rounds an offset up to the next multiple of alignment
+func alignOffset(offset, alignment int) int {
+	return (offset + alignment - 1) / alignment * alignment
+}
+
+//pointerBytes returns a slice over memory that wasn't allocated by Go
+func pointerBytes(address uintptr, size int) []byte {
+	return unsafe.Slice((*byte)(*(*unsafe.Pointer)(unsafe.Pointer(&address))), size)
+}
+
+//pointer returns the address of the struct in memory
+func (s *dllStruct) pointer() uintptr {
+	return uintptr(unsafe.Pointer(&s.data[0]))
+}
+
+//element finds an element by its 1-based index or by its name
+func (s *dllStruct) element(t *Token) *dllStructElement {
+	switch t.Type {
+	case tNUMBER, tDOUBLE:
+		index := t.Int()
+		if index < 1 || index > len(s.elements) {
+			return nil
+		}
+		return s.elements[index-1]
+	}
+	for _, element := range s.elements {
+		if element.name != "" && strings.EqualFold(element.name, t.String()) {
+			return element
+		}
+	}
+	return nil
+}
+
+//getAll returns the whole value of an element, reading char and wchar arrays as strings and byte arrays as binary
+func (s *dllStruct) getAll(element *dllStructElement) *Token {
+	data := s.data[element.offset : element.offset+element.size*element.count]
+	switch element.kind {
+	case "char":
+		return NewToken(tSTRING, decodeString(cString(data, 1), encodingANSI))
+	case "wchar":
+		return NewToken(tSTRING, decodeString(cString(data, 2), encodingUTF16LE))
+	case "byte", "boolean":
+		if element.count > 1 {
+			return NewToken(tBINARY, append([]byte{}, data...))
+		}
+	}
+	return s.get(element, 0)
+}
+
+//get reads a single value of an element
+func (s *dllStruct) get(element *dllStructElement, index int) *Token {
+	data := s.data[element.offset+element.size*index:]
+	switch element.kind {
+	case "byte", "boolean":
+		return NewToken(tNUMBER, int64(data[0]))
+	case "char":
+		return NewToken(tSTRING, decodeString(data[:1], encodingANSI))
+	case "wchar":
+		return NewToken(tSTRING, decodeString(data[:2], encodingUTF16LE))
+	case "short":
+		return NewToken(tNUMBER, int64(int16(binary.LittleEndian.Uint16(data))))
+	case "ushort", "word":
+		return NewToken(tNUMBER, int64(binary.LittleEndian.Uint16(data)))
+	case "int", "long", "bool":
+		return NewToken(tNUMBER, int64(int32(binary.LittleEndian.Uint32(data))))
+	case "uint", "ulong", "dword":
+		return NewToken(tNUMBER, int64(binary.LittleEndian.Uint32(data)))
+	case "int64", "uint64":
+		return NewToken(tNUMBER, int64(binary.LittleEndian.Uint64(data)))
+	case "float":
+		return NewToken(tDOUBLE, float64(math.Float32frombits(binary.LittleEndian.Uint32(data))))
+	case "double":
+		return NewToken(tDOUBLE, math.Float64frombits(binary.LittleEndian.Uint64(data)))
+	}
+
+	//Pointer sized types
+	var value uint64
+	if element.size == 4 {
+		value = uint64(binary.LittleEndian.Uint32(data))
+	} else {
+		value = binary.LittleEndian.Uint64(data)
+	}
+	switch element.kind {
+	case "ptr", "handle":
+		return NewToken(tPTR, fmt.Sprintf("%d", value))
+	case "hwnd":
+		return NewToken(tHWND, fmt.Sprintf("%d", value))
+	case "int_ptr", "long_ptr", "lresult", "lparam":
+		if element.size == 4 {
+			return NewToken(tNUMBER, int64(int32(value)))
+		}
+		return NewToken(tNUMBER, int64(value))
+	}
+	return NewToken(tNUMBER, fmt.Sprintf("%d", value))
+}
+
+//setAll writes the whole value of an element, writing strings into char and wchar arrays and binary into byte arrays
+func (s *dllStruct) setAll(element *dllStructElement, value *Token) {
+	data := s.data[element.offset : element.offset+element.size*element.count]
+	switch element.kind {
+	case "char", "wchar":
+		var encoded []byte
+		if element.kind == "char" {
+			encoded = encodeString(value.String(), encodingANSI)
+		} else {
+			encoded = encodeString(value.String(), encodingUTF16LE)
+		}
+		//The string is truncated to fit, and terminated when there's room left
+		copied := copy(data, encoded)
+		for i := copied; i < len(data) && i < copied+element.size; i++ {
+			data[i] = 0
+		}
+		return
+	case "byte", "boolean":
+		if element.count > 1 && (value.Type == tBINARY || value.Type == tSTRING) {
+			if value.Type == tBINARY {
+				copy(data, value.Bytes())
+			} else {
+				copy(data, encodeString(value.String(), encodingANSI))
+			}
+			return
+		}
+	}
+	s.set(element, 0, value)
+}
+
+//set writes a single value of an element
+func (s *dllStruct) set(element *dllStructElement, index int, value *Token) {
+	data := s.data[element.offset+element.size*index:]
+	switch element.kind {
+	case "char", "wchar":
+		if value.Type == tSTRING {
+			encoding := encodingANSI
+			if element.kind == "wchar" {
+				encoding = encodingUTF16LE
+			}
+			encoded := append(encodeString(value.String(), encoding), 0, 0)
+			copy(data[:element.size], encoded)
+			return
+		}
+	case "float":
+		binary.LittleEndian.PutUint32(data, math.Float32bits(float32(value.Float64())))
+		return
+	case "double":
+		binary.LittleEndian.PutUint64(data, math.Float64bits(value.Float64()))
+		return
+	}
+
+	number := uint64(intValue(value))
+	switch element.size {
+	case 1:
+		data[0] = byte(number)
+	case 2:
+		binary.LittleEndian.PutUint16(data, uint16(number))
+	case 4:
+		binary.LittleEndian.PutUint32(data, uint32(number))
+	case 8:
+		binary.LittleEndian.PutUint64(data, number)
+	}
+}
+
+//cString returns the data before the first NUL character of the given width
+func cString(data []byte, width int) []byte {
+	for i := 0; i+width <= len(data); i += width {
+		terminated := true
+		for _, b := range data[i : i+width] {
+			if b != 0 {
+				terminated = false
+				break
+			}
+		}
+		if terminated {
+			return data[:i]
+		}
+	}
+	return data[:len(data)/width*width]
+}
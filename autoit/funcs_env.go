@@ -0,0 +1,36 @@
+package autoit
+
+import (
+	"os"
+)
+
+func init() {
+	//Environment variables
+	stdFunctions["envget"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "envvariable"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			return NewToken(tSTRING, os.Getenv(args["envvariable"].String())), nil
+		},
+	}
+	stdFunctions["envset"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "envvariable"},
+			&FunctionArg{Name: "value", DefaultValue: NewToken(tDEFAULT, "")},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			//Leaving out the value deletes the variable
+			if args["value"].Type == tDEFAULT {
+				return NewToken(tNUMBER, boolInt(os.Unsetenv(args["envvariable"].String()) == nil)), nil
+			}
+			return NewToken(tNUMBER, boolInt(os.Setenv(args["envvariable"].String(), args["value"].String()) == nil)), nil
+		},
+	}
+	stdFunctions["envupdate"] = &Function{
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			//Child processes already inherit changes made by EnvSet, and other processes can't be told about them
+			return NewToken(tNUMBER, 1), nil
+		},
+	}
+}
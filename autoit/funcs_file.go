@@ -1,21 +1,54 @@
 package autoit
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
 )
 
+//File modes used by FileOpen, some of which are also returned by FileGetEncoding
+const (
+	fileModeRead       = 0
+	fileModeAppend     = 1
+	fileModeOverwrite  = 2
+	fileModeCreatePath = 8
+	fileModeBinary     = 16
+	fileModeUTF16LE    = 32
+	fileModeUTF16BE    = 64
+	fileModeUTF8       = 128
+	fileModeUTF8NoBOM  = 256
+	fileModeANSI       = 512
+	fileModeFullDetect = 16384
+)
+
+//fileHandle holds a file opened by FileOpen
+type fileHandle struct {
+	file     *os.File
+	mode     int
+	encoding int //One of the file modes for encodings
+	reader   *bufio.Reader
+}
+
 func init() {
 	stdFunctions["fileclose"] = &Function{
 		Args: []*FunctionArg{
 			&FunctionArg{Name: "filehandle"},
 		},
 		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
-			file := vm.GetHandle(args["filehandle"].Handle())
-			if file == nil {
+			switch handle := vm.GetHandle(args["filehandle"].Handle()).(type) {
+			case *fileHandle:
+				handle.file.Close()
+			case *fileSearch:
+				//Searches hold no open files, so releasing the handle is enough
+			default:
 				return NewToken(tNUMBER, 0), nil
 			}
-			file.(*os.File).Close()
 			vm.DestroyHandle(args["filehandle"].Handle())
 			return NewToken(tNUMBER, 1), nil
 		},
@@ -25,20 +58,81 @@ func init() {
 			&FunctionArg{Name: "filename"},
 		},
 		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
-			err := os.Remove(args["filename"].String())
-			if err != nil {
+			files := matchFiles(args["filename"].String(), false, false)
+			if len(files) == 0 {
+				return NewToken(tNUMBER, 0), nil
+			}
+			for _, file := range files {
+				if os.Remove(file) != nil {
+					return NewToken(tNUMBER, 0), nil
+				}
+			}
+			return NewToken(tNUMBER, 1), nil
+		},
+	}
+	stdFunctions["fileflush"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "filehandle"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			file, ok := vm.GetHandle(args["filehandle"].Handle()).(*fileHandle)
+			if !ok || file.file.Sync() != nil {
 				return NewToken(tNUMBER, 0), nil
 			}
 			return NewToken(tNUMBER, 1), nil
 		},
 	}
+	stdFunctions["filegetencoding"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "file"},
+			&FunctionArg{Name: "mode", DefaultValue: NewToken(tNUMBER, 1)},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			if file, ok := vm.GetHandle(args["file"].Handle()).(*fileHandle); ok {
+				if file.mode&fileModeBinary != 0 {
+					return NewToken(tNUMBER, fileModeBinary), nil
+				}
+				return NewToken(tNUMBER, file.encoding), nil
+			}
+
+			mode := fileModeRead
+			if args["mode"].Int() == 2 {
+				mode |= fileModeFullDetect
+			}
+			file, err := openFile(args["file"].String(), mode)
+			if err != nil {
+				vm.SetError(1)
+				return NewToken(tNUMBER, -1), nil
+			}
+			file.file.Close()
+			return NewToken(tNUMBER, file.encoding), nil
+		},
+	}
+	stdFunctions["filegetpos"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "filehandle"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			file, ok := vm.GetHandle(args["filehandle"].Handle()).(*fileHandle)
+			if !ok {
+				vm.SetError(1)
+				return NewToken(tNUMBER, 0), nil
+			}
+			pos, err := file.pos()
+			if err != nil {
+				vm.SetError(1)
+				return NewToken(tNUMBER, 0), nil
+			}
+			return NewToken(tNUMBER, pos), nil
+		},
+	}
 	stdFunctions["fileopen"] = &Function{
 		Args: []*FunctionArg{
 			&FunctionArg{Name: "filename"},
 			&FunctionArg{Name: "mode", DefaultValue: NewToken(tNUMBER, 0)},
 		},
 		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
-			file, err := os.OpenFile(args["filename"].String(), os.O_RDWR|os.O_APPEND, 0666)
+			file, err := openFile(args["filename"].String(), args["mode"].Int())
 			if err != nil {
 				return NewToken(tNUMBER, -1), nil
 			}
@@ -52,83 +146,524 @@ func init() {
 			&FunctionArg{Name: "count", DefaultValue: NewToken(tNUMBER, 0)},
 		},
 		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
-			file := &os.File{}
-			close := false
+			file, close, err := vm.fileArg(args["file"], fileModeRead)
+			if err != nil {
+				vm.SetError(1)
+				return NewToken(tSTRING, ""), nil
+			}
+			if close {
+				defer file.file.Close()
+			}
 
-			if args["file"].Type == tHANDLE {
-				file = vm.GetHandle(args["file"].Handle()).(*os.File)
-				if file == nil {
-					vm.SetError(1)
+			data, read, err := file.read(args["count"].Int())
+			vm.SetExtended(read)
+			if err == io.EOF && read == 0 {
+				vm.SetError(-1)
+				return NewToken(tSTRING, ""), nil
+			}
+			if err != nil && err != io.EOF {
+				vm.SetError(1)
+			}
+			return data, nil
+		},
+	}
+	stdFunctions["filereadline"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "file"},
+			&FunctionArg{Name: "line", DefaultValue: NewToken(tDEFAULT, "")},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			file, close, err := vm.fileArg(args["file"], fileModeRead)
+			if err != nil {
+				vm.SetError(1)
+				return NewToken(tSTRING, ""), nil
+			}
+			if close {
+				defer file.file.Close()
+			}
+
+			line := 0
+			if args["line"].Type != tDEFAULT {
+				line = args["line"].Int()
+			} else if close {
+				line = 1
+			}
+
+			if line < 0 {
+				//Read the last line
+				lines, err := file.readLines()
+				if err != nil || len(lines) == 0 {
+					vm.SetError(-1)
 					return NewToken(tSTRING, ""), nil
 				}
-			} else {
-				openFile, err := os.Open(args["file"].String()) //TODO: os.OpenFile() with flags
-				if err != nil {
+				return NewToken(tSTRING, lines[len(lines)-1]), nil
+			}
+			if line > 0 {
+				//Read from the start of the file until the requested line
+				if err := file.rewind(); err != nil {
 					vm.SetError(1)
 					return NewToken(tSTRING, ""), nil
 				}
-				file = openFile
-				close = true
+				for i := 1; i < line; i++ {
+					if _, err := file.readLine(); err != nil {
+						vm.SetError(-1)
+						return NewToken(tSTRING, ""), nil
+					}
+				}
 			}
 
-			fileStat, err := file.Stat()
+			text, err := file.readLine()
 			if err != nil {
-				vm.SetError(1)
+				vm.SetError(-1)
 				return NewToken(tSTRING, ""), nil
 			}
-
-			fileData := make([]byte, fileStat.Size())
-			if args["count"].Int() > 0 {
-				fileData = make([]byte, args["count"].Int())
+			if file.mode&fileModeBinary != 0 {
+				return NewToken(tBINARY, []byte(text)), nil
 			}
-			fileRead, err := file.Read(fileData)
-			if close {
-				file.Close()
+			return NewToken(tSTRING, text), nil
+		},
+	}
+	stdFunctions["filereadtoarray"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "file"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			file, close, err := vm.fileArg(args["file"], fileModeRead)
+			if err != nil {
+				vm.SetError(1)
+				return NewToken(tNUMBER, 0), nil
 			}
-			vm.SetExtended(fileRead)
-			if err == io.EOF {
-				vm.SetError(-1)
-				return NewToken(tSTRING, ""), nil
+			if close {
+				defer file.file.Close()
 			}
+
+			lines, err := file.readLines()
 			if err != nil {
 				vm.SetError(1)
+				return NewToken(tNUMBER, 0), nil
 			}
-			return NewToken(tBINARY, fileData), nil
+			if len(lines) == 0 {
+				vm.SetError(2)
+				return NewToken(tNUMBER, 0), nil
+			}
+
+			values := make([]*Token, len(lines))
+			for i, line := range lines {
+				values[i] = NewToken(tSTRING, line)
+			}
+			vm.SetExtended(len(lines))
+			return vm.NewArray(values), nil
 		},
 	}
-	stdFunctions["filewrite"] = &Function{
+	stdFunctions["filesetend"] = &Function{
 		Args: []*FunctionArg{
-			&FunctionArg{Name: "file"},
-			&FunctionArg{Name: "data"},
+			&FunctionArg{Name: "filehandle"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			file, ok := vm.GetHandle(args["filehandle"].Handle()).(*fileHandle)
+			if !ok {
+				return NewToken(tNUMBER, 0), nil
+			}
+			pos, err := file.pos()
+			if err != nil || file.file.Truncate(pos) != nil {
+				return NewToken(tNUMBER, 0), nil
+			}
+			return NewToken(tNUMBER, 1), nil
+		},
+	}
+	stdFunctions["filesetpos"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "filehandle"},
+			&FunctionArg{Name: "offset"},
+			&FunctionArg{Name: "origin"},
 		},
 		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
-			file := &os.File{}
-			close := false
+			file, ok := vm.GetHandle(args["filehandle"].Handle()).(*fileHandle)
+			if !ok {
+				return NewToken(tNUMBER, 0), nil
+			}
 
-			if args["file"].Type == tHANDLE {
-				file = vm.GetHandle(args["file"].Handle()).(*os.File)
-				if file == nil {
-					vm.SetError(1)
-					return NewToken(tNUMBER, 0), nil
-				}
-			} else {
-				openFile, err := os.OpenFile(args["file"].String(), os.O_RDWR|os.O_CREATE, 0666)
+			//Origins match io.SeekStart, io.SeekCurrent and io.SeekEnd
+			origin := args["origin"].Int()
+			offset := intValue(args["offset"])
+			if origin == io.SeekCurrent {
+				pos, err := file.pos()
 				if err != nil {
-					vm.SetError(1)
 					return NewToken(tNUMBER, 0), nil
 				}
-				file = openFile
-				close = true
+				offset += pos
+				origin = io.SeekStart
+			}
+			if origin != io.SeekStart && origin != io.SeekEnd {
+				return NewToken(tNUMBER, 0), nil
+			}
+			if _, err := file.file.Seek(offset, origin); err != nil {
+				return NewToken(tNUMBER, 0), nil
+			}
+			file.reader.Reset(file.file)
+			return NewToken(tNUMBER, 1), nil
+		},
+	}
+	stdFunctions["filewrite"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "file"},
+			&FunctionArg{Name: "data"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			file, close, err := vm.fileArg(args["file"], fileModeAppend)
+			if err != nil {
+				vm.SetError(1)
+				return NewToken(tNUMBER, 0), nil
 			}
-
-			_, err := file.Write(args["data"].Bytes())
 			if close {
-				file.Close()
+				defer file.file.Close()
+			}
+
+			if file.write(args["data"]) != nil {
+				return NewToken(tNUMBER, 0), nil
 			}
+			return NewToken(tNUMBER, 1), nil
+		},
+	}
+	stdFunctions["filewriteline"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "file"},
+			&FunctionArg{Name: "line"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			file, close, err := vm.fileArg(args["file"], fileModeAppend)
 			if err != nil {
+				vm.SetError(1)
+				return NewToken(tNUMBER, 0), nil
+			}
+			if close {
+				defer file.file.Close()
+			}
+
+			line := args["line"]
+			if line.Type != tBINARY && !strings.HasSuffix(line.String(), "\r") && !strings.HasSuffix(line.String(), "\n") {
+				line = NewToken(tSTRING, line.String()+"\r\n")
+			}
+			if file.write(line) != nil {
 				return NewToken(tNUMBER, 0), nil
 			}
 			return NewToken(tNUMBER, 1), nil
 		},
 	}
-}
\ No newline at end of file
+}
+
+//fileArg returns the file for an argument that accepts either a file handle or a filename,
+//opening the filename with the given mode if needed and reporting whether it must be closed
+func (vm *AutoItVM) fileArg(arg *Token, mode int) (*fileHandle, bool, error) {
+	if arg.Type == tHANDLE {
+		file, ok := vm.GetHandle(arg.Handle()).(*fileHandle)
+		if !ok {
+			return nil, false, os.ErrInvalid
+		}
+		return file, false, nil
+	}
+	file, err := openFile(arg.String(), mode)
+	if err != nil {
+		return nil, false, err
+	}
+	return file, true, nil
+}
+
+//openFile opens a file using FileOpen modes, detecting the encoding of files opened for reading
+func openFile(filename string, mode int) (*fileHandle, error) {
+	flags := os.O_RDONLY
+	switch {
+	case mode&fileModeOverwrite != 0:
+		flags = os.O_RDWR | os.O_CREATE | os.O_TRUNC
+	case mode&fileModeAppend != 0:
+		flags = os.O_RDWR | os.O_CREATE | os.O_APPEND
+	}
+	if mode&fileModeCreatePath != 0 && flags != os.O_RDONLY {
+		if err := os.MkdirAll(filepath.Dir(filename), 0777); err != nil {
+			return nil, err
+		}
+	}
+
+	osFile, err := os.OpenFile(filename, flags, 0666)
+	if err != nil {
+		return nil, err
+	}
+	file := &fileHandle{
+		file:     osFile,
+		mode:     mode,
+		encoding: fileEncoding(mode),
+		reader:   bufio.NewReader(osFile),
+	}
+
+	if flags == os.O_RDONLY || flags&os.O_APPEND != 0 {
+		if err := file.detectEncoding(); err != nil {
+			osFile.Close()
+			return nil, err
+		}
+	}
+	return file, nil
+}
+
+//fileEncoding returns the encoding requested by a FileOpen mode
+func fileEncoding(mode int) int {
+	switch {
+	case mode&fileModeUTF16LE != 0:
+		return fileModeUTF16LE
+	case mode&fileModeUTF16BE != 0:
+		return fileModeUTF16BE
+	case mode&fileModeUTF8 != 0:
+		return fileModeUTF8
+	case mode&fileModeUTF8NoBOM != 0:
+		return fileModeUTF8NoBOM
+	}
+	return fileModeANSI
+}
+
+//fileBOM returns the byte order mark written for an encoding
+func fileBOM(encoding int) []byte {
+	switch encoding {
+	case fileModeUTF16LE:
+		return []byte{0xFF, 0xFE}
+	case fileModeUTF16BE:
+		return []byte{0xFE, 0xFF}
+	case fileModeUTF8:
+		return []byte{0xEF, 0xBB, 0xBF}
+	}
+	return nil
+}
+
+//stringEncoding converts a file encoding into the encoding used by encodeString and decodeString
+func stringEncoding(encoding int) int {
+	switch encoding {
+	case fileModeUTF16LE:
+		return encodingUTF16LE
+	case fileModeUTF16BE:
+		return encodingUTF16BE
+	case fileModeUTF8, fileModeUTF8NoBOM:
+		return encodingUTF8
+	}
+	return encodingANSI
+}
+
+//detectEncoding reads the byte order mark at the start of the file, skipping past it when reading in text mode, and
+//otherwise checks whether the contents are valid UTF-8 unless an encoding was requested
+func (f *fileHandle) detectEncoding() error {
+	if _, err := f.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	f.reader.Reset(f.file)
+
+	header, _ := f.reader.Peek(3)
+	bomLength := 0
+	switch {
+	case f.mode&fileModeBinary != 0:
+		//Binary reads return the contents as they are, including any byte order mark
+	case bytes.HasPrefix(header, fileBOM(fileModeUTF8)):
+		f.encoding = fileModeUTF8
+		bomLength = 3
+	case bytes.HasPrefix(header, fileBOM(fileModeUTF16LE)):
+		f.encoding = fileModeUTF16LE
+		bomLength = 2
+	case bytes.HasPrefix(header, fileBOM(fileModeUTF16BE)):
+		f.encoding = fileModeUTF16BE
+		bomLength = 2
+	case f.mode&(fileModeUTF16LE|fileModeUTF16BE|fileModeUTF8|fileModeUTF8NoBOM|fileModeANSI) == 0:
+		//Only the start of the file is checked unless full detection was requested
+		sample, _ := f.reader.Peek(65536)
+		if f.mode&fileModeFullDetect != 0 {
+			sample, _ = io.ReadAll(f.file)
+			if _, err := f.file.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+			f.reader.Reset(f.file)
+		} else if len(sample) == 65536 {
+			//Don't fail detection on a sequence cut off by the sample size
+			for i := 0; i < utf8.UTFMax && !utf8.Valid(sample); i++ {
+				sample = sample[:len(sample)-1]
+			}
+		}
+		if !isASCII(sample) && utf8.Valid(sample) {
+			f.encoding = fileModeUTF8NoBOM
+		}
+	}
+
+	if f.mode&(fileModeAppend|fileModeOverwrite) != 0 {
+		//Appending continues after the existing contents
+		_, err := f.file.Seek(0, io.SeekEnd)
+		f.reader.Reset(f.file)
+		return err
+	}
+	_, err := f.reader.Discard(bomLength)
+	return err
+}
+
+//isASCII determines whether the given data only holds 7-bit characters
+func isASCII(data []byte) bool {
+	for _, b := range data {
+		if b >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+//pos returns the current read or write position in the file
+func (f *fileHandle) pos() (int64, error) {
+	pos, err := f.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	return pos - int64(f.reader.Buffered()), nil
+}
+
+//rewind moves back to the start of the file, skipping past any byte order mark
+func (f *fileHandle) rewind() error {
+	if _, err := f.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	f.reader.Reset(f.file)
+	if bom := fileBOM(f.encoding); bom != nil {
+		header, _ := f.reader.Peek(len(bom))
+		if bytes.Equal(header, bom) {
+			_, err := f.reader.Discard(len(bom))
+			return err
+		}
+	}
+	return nil
+}
+
+//read reads count characters, or count bytes in binary mode, returning everything left if count is 0
+func (f *fileHandle) read(count int) (*Token, int, error) {
+	if f.mode&fileModeBinary != 0 {
+		var data []byte
+		var err error
+		if count > 0 {
+			data = make([]byte, count)
+			read := 0
+			read, err = io.ReadFull(f.reader, data)
+			data = data[:read]
+			if err == io.ErrUnexpectedEOF {
+				err = io.EOF
+			}
+		} else {
+			data, err = io.ReadAll(f.reader)
+		}
+		return NewToken(tBINARY, data), len(data), err
+	}
+
+	if count <= 0 {
+		data, err := io.ReadAll(f.reader)
+		text := decodeString(data, stringEncoding(f.encoding))
+		return NewToken(tSTRING, text), utf8.RuneCountInString(text), err
+	}
+
+	text := make([]rune, 0)
+	var err error
+	for len(text) < count {
+		var r rune
+		r, err = f.readRune()
+		if err != nil {
+			break
+		}
+		text = append(text, r)
+	}
+	return NewToken(tSTRING, string(text)), len(text), err
+}
+
+//readRune reads a single character using the file encoding
+func (f *fileHandle) readRune() (rune, error) {
+	switch f.encoding {
+	case fileModeUTF16LE, fileModeUTF16BE:
+		readUnit := func() (rune, error) {
+			unit := make([]byte, 2)
+			if _, err := io.ReadFull(f.reader, unit); err != nil {
+				return 0, io.EOF
+			}
+			if f.encoding == fileModeUTF16LE {
+				return rune(binary.LittleEndian.Uint16(unit)), nil
+			}
+			return rune(binary.BigEndian.Uint16(unit)), nil
+		}
+		r, err := readUnit()
+		if err != nil || !utf16.IsSurrogate(r) {
+			return r, err
+		}
+
+		//Decode the rest of the surrogate pair
+		low, err := readUnit()
+		if err != nil {
+			return utf8.RuneError, nil
+		}
+		return utf16.DecodeRune(r, low), nil
+	case fileModeUTF8, fileModeUTF8NoBOM:
+		r, _, err := f.reader.ReadRune()
+		return r, err
+	}
+	b, err := f.reader.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	return []rune(decodeString([]byte{b}, encodingANSI))[0], nil
+}
+
+//readLine reads the next line without its CR, LF or CRLF line ending
+func (f *fileHandle) readLine() (string, error) {
+	line := make([]rune, 0)
+	for {
+		r, err := f.readRune()
+		if err != nil {
+			if len(line) == 0 {
+				return "", err
+			}
+			return string(line), nil
+		}
+		switch r {
+		case '\n':
+			return string(line), nil
+		case '\r':
+			//Consume the LF of a CRLF
+			pos, err := f.pos()
+			if err != nil {
+				return string(line), nil
+			}
+			if next, err := f.readRune(); err == nil && next != '\n' {
+				f.file.Seek(pos, io.SeekStart)
+				f.reader.Reset(f.file)
+			}
+			return string(line), nil
+		}
+		line = append(line, r)
+	}
+}
+
+//readLines reads every remaining line
+func (f *fileHandle) readLines() ([]string, error) {
+	lines := make([]string, 0)
+	for {
+		line, err := f.readLine()
+		if err == io.EOF {
+			return lines, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+}
+
+//write writes binary data as is and strings using the file encoding, writing a byte order mark first if the file is empty
+func (f *fileHandle) write(data *Token) error {
+	if data.Type == tBINARY || f.mode&fileModeBinary != 0 {
+		_, err := f.file.Write(data.Bytes())
+		return err
+	}
+
+	encoded := encodeString(data.String(), stringEncoding(f.encoding))
+	if bom := fileBOM(f.encoding); bom != nil {
+		if stat, err := f.file.Stat(); err == nil && stat.Size() == 0 {
+			encoded = append(bom, encoded...)
+		}
+	}
+	_, err := f.file.Write(encoded)
+	return err
+}
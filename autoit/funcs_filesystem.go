@@ -0,0 +1,590 @@
+package autoit
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+//File time types used by FileGetTime and FileSetTime
+const (
+	fileTimeModified = 0
+	fileTimeCreated  = 1
+	fileTimeAccessed = 2
+)
+
+func init() {
+	//File management
+	stdFunctions["filecopy"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "source"},
+			&FunctionArg{Name: "dest"},
+			&FunctionArg{Name: "flag", DefaultValue: NewToken(tNUMBER, 0)},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			return NewToken(tNUMBER, boolInt(transferFiles(args["source"].String(), args["dest"].String(), args["flag"].Int(), copyFile))), nil
+		},
+	}
+	stdFunctions["fileexists"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "path"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			_, err := os.Stat(args["path"].String())
+			return NewToken(tNUMBER, boolInt(err == nil)), nil
+		},
+	}
+	stdFunctions["filegetattrib"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "filename"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			stat, err := os.Stat(args["filename"].String())
+			if err != nil {
+				vm.SetError(1)
+				return NewToken(tSTRING, ""), nil
+			}
+
+			//Unix permissions and dotfiles stand in for Windows attributes
+			attributes := ""
+			if stat.Mode().Perm()&0200 == 0 {
+				attributes += "R"
+			}
+			if strings.HasPrefix(stat.Name(), ".") {
+				attributes += "H"
+			}
+			if stat.IsDir() {
+				attributes += "D"
+			}
+			if attributes == "" {
+				attributes = "N"
+			}
+			return NewToken(tSTRING, attributes), nil
+		},
+	}
+	stdFunctions["filegetsize"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "filename"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			stat, err := os.Stat(args["filename"].String())
+			if err != nil {
+				vm.SetError(1)
+				return NewToken(tNUMBER, 0), nil
+			}
+			return NewToken(tNUMBER, stat.Size()), nil
+		},
+	}
+	stdFunctions["filegettime"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "filename"},
+			&FunctionArg{Name: "option", DefaultValue: NewToken(tNUMBER, fileTimeModified)},
+			&FunctionArg{Name: "format", DefaultValue: NewToken(tNUMBER, 0)},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			stat, err := os.Stat(args["filename"].String())
+			if err != nil {
+				vm.SetError(1)
+				return NewToken(tSTRING, ""), nil
+			}
+
+			fileTime := stat.ModTime()
+			switch args["option"].Int() {
+			case fileTimeCreated:
+				fileTime = fileCreationTime(stat)
+			case fileTimeAccessed:
+				fileTime = fileAccessTime(stat)
+			}
+
+			if args["format"].Int() == 1 {
+				return NewToken(tSTRING, fileTime.Format("20060102150405")), nil
+			}
+			values := make([]*Token, 0)
+			for _, value := range strings.Split(fileTime.Format("2006 01 02 15 04 05"), " ") {
+				values = append(values, NewToken(tSTRING, value))
+			}
+			return vm.NewArray(values), nil
+		},
+	}
+	stdFunctions["filemove"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "source"},
+			&FunctionArg{Name: "dest"},
+			&FunctionArg{Name: "flag", DefaultValue: NewToken(tNUMBER, 0)},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			return NewToken(tNUMBER, boolInt(transferFiles(args["source"].String(), args["dest"].String(), args["flag"].Int(), moveFile))), nil
+		},
+	}
+	stdFunctions["filesetattrib"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "filepattern"},
+			&FunctionArg{Name: "attributes"},
+			&FunctionArg{Name: "recurse", DefaultValue: NewToken(tNUMBER, 0)},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			readOnly := 0 //1 to set, -1 to clear
+			set := true
+			for _, attribute := range strings.ToUpper(args["attributes"].String()) {
+				switch attribute {
+				case '+':
+					set = true
+				case '-':
+					set = false
+				case 'R':
+					readOnly = 1
+					if !set {
+						readOnly = -1
+					}
+				case 'A', 'S', 'H', 'N', 'O', 'T':
+					//No Unix equivalent, so there's nothing to change
+				default:
+					return NewToken(tNUMBER, 0), nil
+				}
+			}
+
+			files := matchFiles(args["filepattern"].String(), true, args["recurse"].Int() == 1)
+			if len(files) == 0 {
+				return NewToken(tNUMBER, 0), nil
+			}
+			for _, file := range files {
+				stat, err := os.Stat(file)
+				if err != nil {
+					return NewToken(tNUMBER, 0), nil
+				}
+				mode := stat.Mode().Perm()
+				switch readOnly {
+				case 1:
+					mode &^= 0222
+				case -1:
+					mode |= 0200
+				}
+				if os.Chmod(file, mode) != nil {
+					return NewToken(tNUMBER, 0), nil
+				}
+			}
+			return NewToken(tNUMBER, 1), nil
+		},
+	}
+	stdFunctions["filesettime"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "filepattern"},
+			&FunctionArg{Name: "time"},
+			&FunctionArg{Name: "type", DefaultValue: NewToken(tNUMBER, fileTimeModified)},
+			&FunctionArg{Name: "recurse", DefaultValue: NewToken(tNUMBER, 0)},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			newTime := time.Now()
+			if timestamp := args["time"].String(); timestamp != "" {
+				//Missing trailing parts of the time default to zero
+				if len(timestamp) < 14 {
+					timestamp += strings.Repeat("0", 14-len(timestamp))
+				}
+				parsed, err := time.ParseInLocation("20060102150405", timestamp, time.Local)
+				if err != nil {
+					return NewToken(tNUMBER, 0), nil
+				}
+				newTime = parsed
+			}
+
+			timeType := args["type"].Int()
+			if timeType != fileTimeModified && timeType != fileTimeAccessed {
+				//Creation times can't be changed on most Unix filesystems
+				return NewToken(tNUMBER, 0), nil
+			}
+
+			files := matchFiles(args["filepattern"].String(), true, args["recurse"].Int() == 1)
+			if len(files) == 0 {
+				return NewToken(tNUMBER, 0), nil
+			}
+			for _, file := range files {
+				stat, err := os.Stat(file)
+				if err != nil {
+					return NewToken(tNUMBER, 0), nil
+				}
+				modified, accessed := stat.ModTime(), fileAccessTime(stat)
+				if timeType == fileTimeModified {
+					modified = newTime
+				} else {
+					accessed = newTime
+				}
+				if os.Chtimes(file, accessed, modified) != nil {
+					return NewToken(tNUMBER, 0), nil
+				}
+			}
+			return NewToken(tNUMBER, 1), nil
+		},
+	}
+
+	//File searching
+	stdFunctions["filefindfirstfile"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "filename"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			search := newFileSearch(args["filename"].String())
+			if search == nil {
+				vm.SetError(1)
+				return NewToken(tNUMBER, -1), nil
+			}
+			return vm.AddHandle(search), nil
+		},
+	}
+	stdFunctions["filefindnextfile"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "search"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			search, ok := vm.GetHandle(args["search"].Handle()).(*fileSearch)
+			if !ok || search.index >= len(search.entries) {
+				vm.SetError(1)
+				return NewToken(tSTRING, ""), nil
+			}
+			entry := search.entries[search.index]
+			search.index++
+			if entry.IsDir() {
+				vm.SetExtended(1)
+			}
+			return NewToken(tSTRING, entry.Name()), nil
+		},
+	}
+
+	//Directory management
+	stdFunctions["dircopy"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "source"},
+			&FunctionArg{Name: "dest"},
+			&FunctionArg{Name: "flag", DefaultValue: NewToken(tNUMBER, 0)},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			err := copyDir(args["source"].String(), args["dest"].String(), args["flag"].Int() == 1)
+			return NewToken(tNUMBER, boolInt(err == nil)), nil
+		},
+	}
+	stdFunctions["dircreate"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "path"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			err := os.MkdirAll(args["path"].String(), 0777)
+			return NewToken(tNUMBER, boolInt(err == nil)), nil
+		},
+	}
+	stdFunctions["dirgetsize"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "path"},
+			&FunctionArg{Name: "flag", DefaultValue: NewToken(tNUMBER, 0)},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			size, files, dirs := int64(0), 0, 0
+			root := args["path"].String()
+			if stat, err := os.Stat(root); err != nil || !stat.IsDir() {
+				vm.SetError(1)
+				return NewToken(tNUMBER, -1), nil
+			}
+			err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				if path == root {
+					return nil
+				}
+				if info.IsDir() {
+					dirs++
+				} else {
+					files++
+					size += info.Size()
+				}
+				return nil
+			})
+			if err != nil {
+				vm.SetError(1)
+				return NewToken(tNUMBER, -1), nil
+			}
+
+			if args["flag"].Int() == 1 {
+				return vm.NewArray([]*Token{NewToken(tNUMBER, size), NewToken(tNUMBER, files), NewToken(tNUMBER, dirs)}), nil
+			}
+			return NewToken(tNUMBER, size), nil
+		},
+	}
+	stdFunctions["dirmove"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "source"},
+			&FunctionArg{Name: "dest"},
+			&FunctionArg{Name: "flag", DefaultValue: NewToken(tNUMBER, 0)},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			source := args["source"].String()
+			dest := args["dest"].String()
+			if stat, err := os.Stat(source); err != nil || !stat.IsDir() {
+				return NewToken(tNUMBER, 0), nil
+			}
+			if _, err := os.Stat(dest); err == nil {
+				//Existing destinations receive the source inside them when overwriting
+				if args["flag"].Int() != 1 {
+					return NewToken(tNUMBER, 0), nil
+				}
+				dest = filepath.Join(dest, filepath.Base(source))
+			}
+
+			if os.Rename(source, dest) != nil {
+				//Moving between filesystems needs a copy
+				if copyDir(source, dest, true) != nil || os.RemoveAll(source) != nil {
+					return NewToken(tNUMBER, 0), nil
+				}
+			}
+			return NewToken(tNUMBER, 1), nil
+		},
+	}
+	stdFunctions["dirremove"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "path"},
+			&FunctionArg{Name: "recurse", DefaultValue: NewToken(tNUMBER, 0)},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			path := args["path"].String()
+			if stat, err := os.Stat(path); err != nil || !stat.IsDir() {
+				return NewToken(tNUMBER, 0), nil
+			}
+			if args["recurse"].Int() == 1 {
+				return NewToken(tNUMBER, boolInt(os.RemoveAll(path) == nil)), nil
+			}
+			return NewToken(tNUMBER, boolInt(os.Remove(path) == nil)), nil
+		},
+	}
+}
+
+//fileSearch holds the entries found by FileFindFirstFile, returned one at a time by FileFindNextFile
+type fileSearch struct {
+	entries []os.DirEntry
+	index   int
+}
+
+//newFileSearch finds the entries matching a pattern with wildcards in its last element, or nil if none match
+func newFileSearch(pattern string) *fileSearch {
+	dirEntries, err := os.ReadDir(filepath.Dir(pattern))
+	if err != nil {
+		return nil
+	}
+
+	//Names are matched case insensitively, even without wildcards
+	base := filepath.Base(pattern)
+	search := &fileSearch{entries: make([]os.DirEntry, 0)}
+	for _, entry := range dirEntries {
+		if wildcardMatch(base, entry.Name()) {
+			search.entries = append(search.entries, entry)
+		}
+	}
+	if len(search.entries) == 0 {
+		return nil
+	}
+	return search
+}
+
+//hasWildcard determines whether a path contains the * or ? wildcards
+func hasWildcard(path string) bool {
+	return strings.ContainsAny(path, "*?")
+}
+
+//wildcardMatch matches a name against a pattern using AutoIt's case insensitive * and ? wildcards
+func wildcardMatch(pattern, name string) bool {
+	pattern = strings.ToLower(pattern)
+	name = strings.ToLower(name)
+	if pattern == "*.*" {
+		//Matches names with or without an extension, as on Windows
+		return true
+	}
+
+	p, n := []rune(pattern), []rune(name)
+	i, j := 0, 0
+	star, starName := -1, 0
+	for j < len(n) {
+		switch {
+		case i < len(p) && (p[i] == '?' || p[i] == n[j]):
+			i++
+			j++
+		case i < len(p) && p[i] == '*':
+			star, starName = i, j
+			i++
+		case star > -1:
+			//Let the last star consume another character and try again
+			i = star + 1
+			starName++
+			j = starName
+		default:
+			return false
+		}
+	}
+	for i < len(p) && p[i] == '*' {
+		i++
+	}
+	return i == len(p)
+}
+
+//matchFiles returns the paths matching a pattern that may contain wildcards in its last element,
+//optionally including directories and matching the same pattern in every subdirectory
+func matchFiles(pattern string, dirs, recurse bool) []string {
+	if !hasWildcard(filepath.Base(pattern)) {
+		stat, err := os.Stat(pattern)
+		if err != nil {
+			return nil
+		}
+		matches := make([]string, 0)
+		if dirs || !stat.IsDir() {
+			matches = append(matches, pattern)
+		}
+		if recurse && stat.IsDir() {
+			matches = append(matches, matchFiles(filepath.Join(pattern, "*"), dirs, true)...)
+		}
+		return matches
+	}
+
+	matches := make([]string, 0)
+	dir, base := filepath.Dir(pattern), filepath.Base(pattern)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if (dirs || !entry.IsDir()) && wildcardMatch(base, entry.Name()) {
+			matches = append(matches, path)
+		}
+		if recurse && entry.IsDir() {
+			matches = append(matches, matchFiles(filepath.Join(path, base), dirs, true)...)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+//transferFiles copies or moves the files matching source into dest, which may be a directory or a
+//wildcard pattern for the new names, following FileCopy and FileMove flags for overwriting and creating dest
+func transferFiles(source, dest string, flag int, transfer func(string, string, bool) error) bool {
+	overwrite := flag&1 != 0
+	createPath := flag&8 != 0
+
+	files := matchFiles(source, false, false)
+	if len(files) == 0 {
+		return false
+	}
+
+	destDir, destPattern := dest, ""
+	if hasWildcard(filepath.Base(dest)) {
+		destDir, destPattern = filepath.Dir(dest), filepath.Base(dest)
+	} else if stat, err := os.Stat(dest); (err == nil && stat.IsDir()) || strings.HasSuffix(dest, string(os.PathSeparator)) {
+		destPattern = "*"
+	} else if len(files) > 1 || hasWildcard(filepath.Base(source)) {
+		//Multiple files can only be transferred into a directory
+		destPattern = "*"
+	} else {
+		destDir = filepath.Dir(dest)
+	}
+
+	if createPath {
+		if err := os.MkdirAll(destDir, 0777); err != nil {
+			return false
+		}
+	}
+
+	success := true
+	for _, file := range files {
+		target := dest
+		if destPattern != "" {
+			target = filepath.Join(destDir, wildcardName(destPattern, filepath.Base(file)))
+		}
+		if transfer(file, target, overwrite) != nil {
+			success = false
+		}
+	}
+	return success
+}
+
+//wildcardName builds a destination filename from a pattern such as *.* or *.bak
+func wildcardName(pattern, name string) string {
+	if pattern == "*" || pattern == "*.*" {
+		return name
+	}
+	if strings.HasPrefix(pattern, "*.") && !hasWildcard(pattern[2:]) {
+		return strings.TrimSuffix(name, filepath.Ext(name)) + pattern[1:]
+	}
+	return name
+}
+
+//copyFile copies a file's contents and permissions, failing if dest exists unless overwriting
+func copyFile(source, dest string, overwrite bool) error {
+	stat, err := os.Stat(source)
+	if err != nil {
+		return err
+	}
+	if stat.IsDir() {
+		return fmt.Errorf("%s is a directory", source)
+	}
+
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !overwrite {
+		flags |= os.O_EXCL
+	}
+	out, err := os.OpenFile(dest, flags, stat.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+//moveFile moves a file, copying it if it has to cross filesystems
+func moveFile(source, dest string, overwrite bool) error {
+	if !overwrite {
+		if _, err := os.Stat(dest); err == nil {
+			return os.ErrExist
+		}
+	}
+	if os.Rename(source, dest) == nil {
+		return nil
+	}
+	if err := copyFile(source, dest, overwrite); err != nil {
+		return err
+	}
+	return os.Remove(source)
+}
+
+//copyDir copies a directory tree, failing on existing files unless overwriting
+func copyDir(source, dest string, overwrite bool) error {
+	stat, err := os.Stat(source)
+	if err != nil {
+		return err
+	}
+	if !stat.IsDir() {
+		return fmt.Errorf("%s is not a directory", source)
+	}
+
+	return filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		}
+		return copyFile(path, target, overwrite)
+	})
+}
@@ -0,0 +1,346 @@
+package autoit
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+//defaultUserAgent is sent with every request until HttpSetUserAgent changes it
+const defaultUserAgent = "AutoIt"
+
+//Options used by InetRead, InetGet and InetGetSize, with ASCII and binary transfers only mattering for FTP
+const (
+	inetForceReload = 1
+	inetIgnoreSSL   = 2
+)
+
+//Proxy modes used by HttpSetProxy
+const (
+	proxyDefault = 0
+	proxyNone    = 1
+	proxyCustom  = 2
+)
+
+//Indexes of the values returned by InetGetInfo
+const (
+	inetBytesRead = 0
+	inetSize      = 1
+	inetComplete  = 2
+	inetSuccess   = 3
+	inetError     = 4
+	inetExtended  = 5
+)
+
+//httpConfig holds the HTTP settings shared by every scope of a script
+type httpConfig struct {
+	userAgent  string
+	proxyMode  int
+	proxy      *url.URL
+	mutex      sync.Mutex
+	transports [2]*http.Transport //Reused by every request, verifying certificates or not
+}
+
+//inetDownload tracks a download started by InetGet in the background
+type inetDownload struct {
+	mutex             sync.Mutex
+	read, size        int64
+	complete, success bool
+	err, extended     int
+	cancel            context.CancelFunc
+}
+
+func init() {
+	//Internet
+	stdFunctions["inetread"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "url"},
+			&FunctionArg{Name: "options", DefaultValue: NewToken(tNUMBER, 0)},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			resp, err := vm.httpRequest(vm.context(), "GET", args["url"].String(), args["options"].Int())
+			if err != nil {
+				vm.SetError(1)
+				return NewToken(tSTRING, ""), nil
+			}
+			defer resp.Body.Close()
+			data, err := io.ReadAll(resp.Body)
+			if err != nil {
+				vm.SetError(1)
+				return NewToken(tSTRING, ""), nil
+			}
+			vm.SetExtended(len(data))
+			return NewToken(tBINARY, data), nil
+		},
+	}
+	stdFunctions["inetget"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "url"},
+			&FunctionArg{Name: "filename"},
+			&FunctionArg{Name: "options", DefaultValue: NewToken(tNUMBER, 0)},
+			&FunctionArg{Name: "background", DefaultValue: NewToken(tNUMBER, 0)},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			ctx, cancel := context.WithCancel(vm.context())
+			download := &inetDownload{cancel: cancel}
+			request := func() (*http.Response, error) {
+				return vm.httpRequest(ctx, "GET", args["url"].String(), args["options"].Int())
+			}
+			filename := args["filename"].String()
+
+			if args["background"].Int() == 1 {
+				//Failures are reported by InetGetInfo, as the request is only sent once the download has started
+				go download.fetch(request, filename)
+				return vm.AddHandle(download), nil
+			}
+
+			download.fetch(request, filename)
+			if !download.success {
+				vm.SetError(download.err)
+			}
+			return NewToken(tNUMBER, download.read), nil
+		},
+	}
+	stdFunctions["inetgetinfo"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "handle", DefaultValue: NewToken(tNUMBER, -1)},
+			&FunctionArg{Name: "index", DefaultValue: NewToken(tNUMBER, -1)},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			//Without a handle, the number of running downloads is returned
+			if args["handle"].Type != tHANDLE {
+				running := 0
+				for _, handle := range vm.handles {
+					if download, ok := handle.(*inetDownload); ok && !download.info()[inetComplete].Bool() {
+						running++
+					}
+				}
+				return NewToken(tNUMBER, running), nil
+			}
+
+			download, ok := vm.GetHandle(args["handle"].Handle()).(*inetDownload)
+			if !ok {
+				vm.SetError(1)
+				return NewToken(tNUMBER, 0), nil
+			}
+			info := download.info()
+			index := args["index"].Int()
+			if index == -1 {
+				return vm.NewArray(info), nil
+			}
+			if index < 0 || index >= len(info) {
+				vm.SetError(1)
+				return NewToken(tNUMBER, 0), nil
+			}
+			return info[index], nil
+		},
+	}
+	stdFunctions["inetgetsize"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "url"},
+			&FunctionArg{Name: "options", DefaultValue: NewToken(tNUMBER, 0)},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			resp, err := vm.httpRequest(vm.context(), "HEAD", args["url"].String(), args["options"].Int())
+			if err != nil {
+				vm.SetError(1)
+				return NewToken(tNUMBER, 0), nil
+			}
+			resp.Body.Close()
+			if resp.ContentLength < 0 {
+				//The server didn't say
+				return NewToken(tNUMBER, 0), nil
+			}
+			return NewToken(tNUMBER, resp.ContentLength), nil
+		},
+	}
+	stdFunctions["inetclose"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "handle"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			download, ok := vm.GetHandle(args["handle"].Handle()).(*inetDownload)
+			if !ok {
+				return NewToken(tBOOLEAN, false), nil
+			}
+			aborted := !download.info()[inetComplete].Bool()
+			download.cancel()
+			vm.DestroyHandle(args["handle"].Handle())
+			return NewToken(tBOOLEAN, aborted), nil
+		},
+	}
+	stdFunctions["httpsetuseragent"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "useragent"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			vm.httpConfig.userAgent = args["useragent"].String()
+			return NewToken(tNUMBER, 1), nil
+		},
+	}
+	stdFunctions["httpsetproxy"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "mode", DefaultValue: NewToken(tNUMBER, proxyDefault)},
+			&FunctionArg{Name: "proxy", DefaultValue: NewToken(tSTRING, "")},
+			&FunctionArg{Name: "username", DefaultValue: NewToken(tSTRING, "")},
+			&FunctionArg{Name: "password", DefaultValue: NewToken(tSTRING, "")},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			mode := args["mode"].Int()
+			switch mode {
+			case proxyDefault, proxyNone:
+				vm.httpConfig.setProxy(mode, nil)
+				return NewToken(tNUMBER, 1), nil
+			case proxyCustom:
+				//Proxies are given as host:port
+				proxy := args["proxy"].String()
+				if !strings.Contains(proxy, "://") {
+					proxy = "http://" + proxy
+				}
+				proxyURL, err := url.Parse(proxy)
+				if err != nil || proxyURL.Host == "" {
+					vm.SetError(1)
+					return NewToken(tNUMBER, 0), nil
+				}
+				if args["username"].String() != "" {
+					proxyURL.User = url.UserPassword(args["username"].String(), args["password"].String())
+				}
+				vm.httpConfig.setProxy(mode, proxyURL)
+				return NewToken(tNUMBER, 1), nil
+			}
+			vm.SetError(1)
+			return NewToken(tNUMBER, 0), nil
+		},
+	}
+}
+
+//httpRequest sends a request using the HTTP settings of the script and InetRead options, failing on error statuses
+func (vm *AutoItVM) httpRequest(ctx context.Context, method, address string, options int) (*http.Response, error) {
+	transport := vm.httpConfig.transport(options&inetIgnoreSSL != 0)
+	req, err := http.NewRequestWithContext(ctx, method, address, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", vm.httpConfig.userAgent)
+	if options&inetForceReload != 0 {
+		req.Header.Set("Cache-Control", "no-cache")
+		req.Header.Set("Pragma", "no-cache")
+	}
+
+	resp, err := (&http.Client{Transport: transport}).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("http: %s", resp.Status)
+	}
+	return resp, nil
+}
+
+//transport returns the transport for requests with the current proxy settings, building it on first use so
+//connections are kept alive between requests
+func (c *httpConfig) transport(ignoreSSL bool) *http.Transport {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	index := 0
+	if ignoreSSL {
+		index = 1
+	}
+	if c.transports[index] != nil {
+		return c.transports[index]
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	switch c.proxyMode {
+	case proxyNone:
+		transport.Proxy = nil
+	case proxyCustom:
+		transport.Proxy = http.ProxyURL(c.proxy)
+	}
+	if ignoreSSL {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	c.transports[index] = transport
+	return transport
+}
+
+//setProxy changes the proxy settings, closing the connections of the transports built with the old ones
+func (c *httpConfig) setProxy(mode int, proxy *url.URL) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.proxyMode = mode
+	c.proxy = proxy
+	for i, transport := range c.transports {
+		if transport != nil {
+			transport.CloseIdleConnections()
+			c.transports[i] = nil
+		}
+	}
+}
+
+//fetch sends a request and downloads its response into a file, tracking its progress
+func (d *inetDownload) fetch(request func() (*http.Response, error), filename string) {
+	resp, err := request()
+	if err != nil {
+		d.finish(err)
+		return
+	}
+	defer resp.Body.Close()
+	file, err := os.Create(filename)
+	if err != nil {
+		d.finish(err)
+		return
+	}
+	if resp.ContentLength > 0 {
+		d.mutex.Lock()
+		d.size = resp.ContentLength
+		d.mutex.Unlock()
+	}
+
+	_, err = io.Copy(io.MultiWriter(file, d), resp.Body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	d.finish(err)
+}
+
+//finish marks a download as complete, failed if err isn't nil
+func (d *inetDownload) finish(err error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.complete = true
+	d.success = err == nil
+	if err != nil {
+		d.err = 1
+	}
+	d.cancel()
+}
+
+//Write counts the bytes downloaded so far
+func (d *inetDownload) Write(data []byte) (int, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.read += int64(len(data))
+	return len(data), nil
+}
+
+//info returns the values reported by InetGetInfo
+func (d *inetDownload) info() []*Token {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return []*Token{
+		inetBytesRead: NewToken(tNUMBER, d.read),
+		inetSize:      NewToken(tNUMBER, d.size),
+		inetComplete:  NewToken(tBOOLEAN, d.complete),
+		inetSuccess:   NewToken(tBOOLEAN, d.success),
+		inetError:     NewToken(tNUMBER, d.err),
+		inetExtended:  NewToken(tNUMBER, d.extended),
+	}
+}
@@ -0,0 +1,39 @@
+package autoit
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestInetReadReusesConnections(t *testing.T) {
+	var connections int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "a")
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&connections, 1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	script := ""
+	for i := 0; i < 5; i++ {
+		script += "ConsoleWrite(BinaryToString(InetRead(" + quote(server.URL) + ")))\n"
+	}
+	out, err := runScript(t, script, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "aaaaa" {
+		t.Errorf("output = %q, want %q", out, "aaaaa")
+	}
+	if made := atomic.LoadInt32(&connections); made != 1 {
+		t.Errorf("%d connections made, want 1", made)
+	}
+}
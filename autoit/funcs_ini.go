@@ -0,0 +1,363 @@
+package autoit
+
+import (
+	"os"
+	"strings"
+)
+
+//iniFile holds the lines of an INI file so unrelated lines survive being written back
+type iniFile struct {
+	lines    []string
+	encoding int //One of the file modes for encodings
+}
+
+func init() {
+	//INI files
+	stdFunctions["iniread"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "filename"},
+			&FunctionArg{Name: "section"},
+			&FunctionArg{Name: "key"},
+			&FunctionArg{Name: "default"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			ini, err := readIni(args["filename"].String())
+			if err != nil {
+				return args["default"], nil
+			}
+			keys, values, ok := ini.section(args["section"].String())
+			if !ok {
+				return args["default"], nil
+			}
+			for i, key := range keys {
+				if strings.EqualFold(key, args["key"].String()) {
+					return NewToken(tSTRING, values[i]), nil
+				}
+			}
+			return args["default"], nil
+		},
+	}
+	stdFunctions["iniwrite"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "filename"},
+			&FunctionArg{Name: "section"},
+			&FunctionArg{Name: "key"},
+			&FunctionArg{Name: "value"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			filename := args["filename"].String()
+			ini, err := readIni(filename)
+			if err != nil {
+				if !os.IsNotExist(err) {
+					return NewToken(tNUMBER, 0), nil
+				}
+				ini = &iniFile{lines: make([]string, 0), encoding: fileModeANSI}
+			}
+
+			key := strings.TrimSpace(args["key"].String())
+			line := key + "=" + args["value"].String()
+			start, end, ok := ini.sectionRange(args["section"].String())
+			if !ok {
+				ini.lines = append(ini.lines, "["+args["section"].String()+"]", line)
+				return NewToken(tNUMBER, boolInt(ini.save(filename) == nil)), nil
+			}
+
+			replaced := false
+			for i := start + 1; i < end; i++ {
+				if lineKey, _, isKey := iniKeyValue(ini.lines[i]); isKey && strings.EqualFold(lineKey, key) {
+					//Existing keys keep their original case
+					ini.lines[i] = lineKey + "=" + args["value"].String()
+					replaced = true
+					break
+				}
+			}
+			if !replaced {
+				ini.insert(ini.contentEnd(start, end), line)
+			}
+			return NewToken(tNUMBER, boolInt(ini.save(filename) == nil)), nil
+		},
+	}
+	stdFunctions["inidelete"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "filename"},
+			&FunctionArg{Name: "section"},
+			&FunctionArg{Name: "key", DefaultValue: NewToken(tDEFAULT, "")},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			filename := args["filename"].String()
+			ini, err := readIni(filename)
+			if err != nil {
+				return NewToken(tNUMBER, 0), nil
+			}
+			start, end, ok := ini.sectionRange(args["section"].String())
+			if !ok {
+				//Nothing to delete still counts as success
+				return NewToken(tNUMBER, 1), nil
+			}
+
+			if args["key"].Type == tDEFAULT {
+				ini.lines = append(ini.lines[:start], ini.lines[end:]...)
+			} else {
+				for i := start + 1; i < end; i++ {
+					if lineKey, _, isKey := iniKeyValue(ini.lines[i]); isKey && strings.EqualFold(lineKey, args["key"].String()) {
+						ini.lines = append(ini.lines[:i], ini.lines[i+1:]...)
+						break
+					}
+				}
+			}
+			return NewToken(tNUMBER, boolInt(ini.save(filename) == nil)), nil
+		},
+	}
+	stdFunctions["inireadsection"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "filename"},
+			&FunctionArg{Name: "section"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			ini, err := readIni(args["filename"].String())
+			if err != nil {
+				vm.SetError(1)
+				return NewToken(tNUMBER, 0), nil
+			}
+			keys, values, ok := ini.section(args["section"].String())
+			if !ok {
+				vm.SetError(1)
+				return NewToken(tNUMBER, 0), nil
+			}
+
+			//Each row holds a key and its value, with the count in the first row
+			rows := []*Token{vm.NewArray([]*Token{NewToken(tNUMBER, len(keys)), NewToken(tSTRING, "")})}
+			for i, key := range keys {
+				rows = append(rows, vm.NewArray([]*Token{NewToken(tSTRING, key), NewToken(tSTRING, values[i])}))
+			}
+			return vm.NewArray(rows), nil
+		},
+	}
+	stdFunctions["inireadsectionnames"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "filename"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			ini, err := readIni(args["filename"].String())
+			if err != nil {
+				vm.SetError(1)
+				return NewToken(tNUMBER, 0), nil
+			}
+			names := []*Token{NewToken(tNUMBER, 0)}
+			for _, line := range ini.lines {
+				if name, ok := iniSectionName(line); ok {
+					names = append(names, NewToken(tSTRING, name))
+				}
+			}
+			if len(names) == 1 {
+				vm.SetError(1)
+				return NewToken(tNUMBER, 0), nil
+			}
+			names[0] = NewToken(tNUMBER, len(names)-1)
+			return vm.NewArray(names), nil
+		},
+	}
+	stdFunctions["inirenamesection"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "filename"},
+			&FunctionArg{Name: "section"},
+			&FunctionArg{Name: "newsection"},
+			&FunctionArg{Name: "flag", DefaultValue: NewToken(tNUMBER, 0)},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			filename := args["filename"].String()
+			ini, err := readIni(filename)
+			if err != nil {
+				return NewToken(tNUMBER, 0), nil
+			}
+			if _, _, ok := ini.sectionRange(args["section"].String()); !ok {
+				return NewToken(tNUMBER, 0), nil
+			}
+
+			if start, end, ok := ini.sectionRange(args["newsection"].String()); ok && !strings.EqualFold(args["section"].String(), args["newsection"].String()) {
+				//An existing section with the new name is only replaced when overwriting
+				if args["flag"].Int() != 1 {
+					vm.SetError(1)
+					return NewToken(tNUMBER, 0), nil
+				}
+				ini.lines = append(ini.lines[:start], ini.lines[end:]...)
+			}
+			start, _, _ := ini.sectionRange(args["section"].String())
+			ini.lines[start] = "[" + args["newsection"].String() + "]"
+			return NewToken(tNUMBER, boolInt(ini.save(filename) == nil)), nil
+		},
+	}
+	stdFunctions["iniwritesection"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "filename"},
+			&FunctionArg{Name: "section"},
+			&FunctionArg{Name: "data"},
+			&FunctionArg{Name: "index", DefaultValue: NewToken(tNUMBER, 1)},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			lines := make([]string, 0)
+			if rows := vm.GetArray(args["data"]); rows != nil {
+				//Arrays hold a key and value in each row, starting from the given index
+				index := args["index"].Int()
+				if index < 0 || index > len(rows) {
+					vm.SetError(1)
+					return NewToken(tNUMBER, 0), nil
+				}
+				for _, row := range rows[index:] {
+					values := vm.GetArray(row)
+					if len(values) < 2 || values[0] == nil {
+						vm.SetError(1)
+						return NewToken(tNUMBER, 0), nil
+					}
+					value := ""
+					if values[1] != nil {
+						value = values[1].String()
+					}
+					lines = append(lines, strings.TrimSpace(values[0].String())+"="+value)
+				}
+			} else {
+				//Strings hold key=value pairs separated by line feeds
+				for _, line := range strings.Split(args["data"].String(), "\n") {
+					line = strings.TrimSuffix(line, "\r")
+					if line == "" {
+						continue
+					}
+					if !strings.Contains(line, "=") {
+						vm.SetError(1)
+						return NewToken(tNUMBER, 0), nil
+					}
+					lines = append(lines, line)
+				}
+			}
+
+			filename := args["filename"].String()
+			ini, err := readIni(filename)
+			if err != nil {
+				if !os.IsNotExist(err) {
+					return NewToken(tNUMBER, 0), nil
+				}
+				ini = &iniFile{lines: make([]string, 0), encoding: fileModeANSI}
+			}
+			start, end, ok := ini.sectionRange(args["section"].String())
+			if !ok {
+				ini.lines = append(ini.lines, "["+args["section"].String()+"]")
+				ini.lines = append(ini.lines, lines...)
+				return NewToken(tNUMBER, boolInt(ini.save(filename) == nil)), nil
+			}
+
+			//The previous contents of the section are replaced, keeping any blank lines after it
+			contentEnd := ini.contentEnd(start, end)
+			ini.lines = append(ini.lines[:start+1], append(lines, ini.lines[contentEnd:]...)...)
+			return NewToken(tNUMBER, boolInt(ini.save(filename) == nil)), nil
+		},
+	}
+}
+
+//readIni reads the lines of an INI file, detecting its encoding
+func readIni(filename string) (*iniFile, error) {
+	file, err := openFile(filename, fileModeRead)
+	if err != nil {
+		return nil, err
+	}
+	defer file.file.Close()
+
+	lines, err := file.readLines()
+	if err != nil {
+		return nil, err
+	}
+	return &iniFile{lines: lines, encoding: file.encoding}, nil
+}
+
+//save writes the lines of an INI file back using its original encoding and Windows line endings
+func (ini *iniFile) save(filename string) error {
+	data := strings.Join(ini.lines, "\r\n")
+	if len(ini.lines) > 0 {
+		data += "\r\n"
+	}
+	return os.WriteFile(filename, append(fileBOM(ini.encoding), encodeString(data, stringEncoding(ini.encoding))...), 0666)
+}
+
+//sectionRange returns the line of the first section header matching a name and the line where the section ends
+func (ini *iniFile) sectionRange(section string) (int, int, bool) {
+	section = strings.TrimSpace(section)
+	for start, line := range ini.lines {
+		if name, ok := iniSectionName(line); ok && strings.EqualFold(name, section) {
+			end := start + 1
+			for end < len(ini.lines) {
+				if _, ok := iniSectionName(ini.lines[end]); ok {
+					break
+				}
+				end++
+			}
+			return start, end, true
+		}
+	}
+	return 0, 0, false
+}
+
+//contentEnd returns the line after the last non-blank line of a section, so new keys go before any spacing
+func (ini *iniFile) contentEnd(start, end int) int {
+	for end > start+1 && strings.TrimSpace(ini.lines[end-1]) == "" {
+		end--
+	}
+	return end
+}
+
+//insert inserts a line before the given line
+func (ini *iniFile) insert(pos int, line string) {
+	ini.lines = append(ini.lines, "")
+	copy(ini.lines[pos+1:], ini.lines[pos:])
+	ini.lines[pos] = line
+}
+
+//section returns the keys and values of the first section matching a name, ignoring duplicate keys as Windows does
+func (ini *iniFile) section(section string) ([]string, []string, bool) {
+	start, end, ok := ini.sectionRange(section)
+	if !ok {
+		return nil, nil, false
+	}
+	keys := make([]string, 0)
+	values := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, line := range ini.lines[start+1 : end] {
+		key, value, isKey := iniKeyValue(line)
+		if !isKey || seen[strings.ToLower(key)] {
+			continue
+		}
+		seen[strings.ToLower(key)] = true
+		keys = append(keys, key)
+		values = append(values, value)
+	}
+	return keys, values, true
+}
+
+//iniSectionName returns the name of the section if the line is a section header
+func iniSectionName(line string) (string, bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "[") {
+		return "", false
+	}
+	end := strings.Index(line, "]")
+	if end < 0 {
+		return "", false
+	}
+	return strings.TrimSpace(line[1:end]), true
+}
+
+//iniKeyValue splits a key=value line, skipping comments and removing the quotes around quoted values
+func iniKeyValue(line string) (string, string, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, ";") {
+		return "", "", false
+	}
+	equals := strings.Index(line, "=")
+	if equals < 0 {
+		return "", "", false
+	}
+	key := strings.TrimSpace(line[:equals])
+	value := strings.TrimSpace(line[equals+1:])
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		value = value[1 : len(value)-1]
+	}
+	return key, value, true
+}
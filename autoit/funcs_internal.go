@@ -1,9 +1,11 @@
 package autoit
 
 import (
+	"encoding/binary"
 	"fmt"
 	"math"
 	"os"
+	"strconv"
 	"strings"
 )
 
@@ -14,15 +16,83 @@ func init() {
 			&FunctionArg{Name: "expression"},
 		},
 		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
-			return NewToken(tBINARY, args["expression"].Bytes()), nil
+			expression := args["expression"]
+			switch expression.Type {
+			case tSTRING:
+				return NewToken(tBINARY, binaryData(expression)), nil
+			case tNUMBER, tDOUBLE:
+				//Numbers are stored little endian with the width of their type
+				if number := expression.Float64(); !isWholeNumber(number) {
+					data := make([]byte, 8)
+					binary.LittleEndian.PutUint64(data, math.Float64bits(number))
+					return NewToken(tBINARY, data), nil
+				}
+				if number := intValue(expression); isInt32(number) {
+					data := make([]byte, 4)
+					binary.LittleEndian.PutUint32(data, uint32(number))
+					return NewToken(tBINARY, data), nil
+				} else {
+					data := make([]byte, 8)
+					binary.LittleEndian.PutUint64(data, uint64(number))
+					return NewToken(tBINARY, data), nil
+				}
+			}
+			return NewToken(tBINARY, expression.Bytes()), nil
+		},
+	}
+	stdFunctions["hwnd"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "expression"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			expression := args["expression"]
+			switch expression.Type {
+			case tPTR, tHWND, tNUMBER, tDOUBLE, tBINARY:
+			case tSTRING:
+				if _, ok := hexBytes(expression.String()); !ok {
+					vm.SetError(1)
+					return NewToken(tHWND, "0"), nil
+				}
+			default:
+				vm.SetError(1)
+				return NewToken(tHWND, "0"), nil
+			}
+			return NewToken(tHWND, fmt.Sprintf("%d", uint64(intValue(expression)))), nil
 		},
 	}
 	stdFunctions["number"] = &Function{
 		Args: []*FunctionArg{
 			&FunctionArg{Name: "expression"},
+			&FunctionArg{Name: "flag", DefaultValue: NewToken(tNUMBER, 0)},
 		},
 		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
-			return NewToken(tDOUBLE, args["expression"].Float64()), nil
+			expression := args["expression"]
+			number := expression.Float64()
+			switch expression.Type {
+			case tBINARY, tBOOLEAN, tPTR, tHWND:
+				number = float64(intValue(expression))
+			}
+
+			switch args["flag"].Int() {
+			case 1: //32-bit integer
+				return NewToken(tNUMBER, int64(int32(int64(number)))), nil
+			case 2: //64-bit integer
+				return NewToken(tNUMBER, int64(number)), nil
+			case 3: //Double
+				return NewDouble(number), nil
+			}
+			if isWholeNumber(number) && expression.Type != tDOUBLE {
+				return NewToken(tNUMBER, intValue(expression)), nil
+			}
+			return NewDouble(number), nil
+		},
+	}
+	stdFunctions["ptr"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "expression"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			return NewToken(tPTR, fmt.Sprintf("%d", uint64(intValue(args["expression"])))), nil
 		},
 	}
 	stdFunctions["string"] = &Function{
@@ -30,30 +100,170 @@ func init() {
 			&FunctionArg{Name: "expression"},
 		},
 		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
-			return NewToken(tSTRING, string(args["expression"].Bytes())), nil
+			switch args["expression"].Type {
+			case tHANDLE, tDEFAULT, tNULL:
+				return NewToken(tSTRING, ""), nil
+			}
+			return NewToken(tSTRING, args["expression"].String()), nil
 		},
 	}
 
 	//Type checks
+	stdFunctions["isadmin"] = &Function{
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			//Geteuid returns -1 on Windows
+			if os.Geteuid() == 0 {
+				return NewToken(tNUMBER, 1), nil
+			}
+			return NewToken(tNUMBER, 0), nil
+		},
+	}
+	stdFunctions["isarray"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "variable"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			return NewToken(tNUMBER, boolInt(vm.varType(args["variable"]) == "Array")), nil
+		},
+	}
+	stdFunctions["isbinary"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "expression"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			return NewToken(tNUMBER, boolInt(args["expression"].Type == tBINARY)), nil
+		},
+	}
+	stdFunctions["isbool"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "expression"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			return NewToken(tNUMBER, boolInt(args["expression"].Type == tBOOLEAN)), nil
+		},
+	}
+	stdFunctions["isfloat"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "expression"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			//Floats holding whole numbers aren't considered floats
+			isNumber := args["expression"].Type == tNUMBER || args["expression"].Type == tDOUBLE
+			return NewToken(tNUMBER, boolInt(isNumber && !isWholeNumber(args["expression"].Float64()))), nil
+		},
+	}
+	stdFunctions["isfunc"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "function"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			switch args["function"].Type {
+			case tUDF:
+				return NewToken(tNUMBER, 1), nil
+			case tCALL:
+				return NewToken(tNUMBER, 2), nil
+			}
+			return NewToken(tNUMBER, 0), nil
+		},
+	}
+	stdFunctions["ishwnd"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "expression"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			return NewToken(tNUMBER, boolInt(args["expression"].Type == tHWND)), nil
+		},
+	}
+	stdFunctions["isint"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "expression"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			//Floats holding whole numbers are considered integers
+			isNumber := args["expression"].Type == tNUMBER || args["expression"].Type == tDOUBLE
+			return NewToken(tNUMBER, boolInt(isNumber && isWholeNumber(args["expression"].Float64()))), nil
+		},
+	}
+	stdFunctions["iskeyword"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "variable", DefaultValue: NewToken(tDEFAULT, "")},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			switch args["variable"].Type {
+			case tDEFAULT:
+				return NewToken(tNUMBER, 1), nil
+			case tNULL:
+				return NewToken(tNUMBER, 2), nil
+			}
+			return NewToken(tNUMBER, 0), nil
+		},
+	}
+	stdFunctions["ismap"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "variable"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			return NewToken(tNUMBER, boolInt(vm.varType(args["variable"]) == "Map")), nil
+		},
+	}
+	stdFunctions["isnumber"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "variable"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			isNumber := args["variable"].Type == tNUMBER || args["variable"].Type == tDOUBLE
+			return NewToken(tNUMBER, boolInt(isNumber)), nil
+		},
+	}
+	stdFunctions["isobj"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "variable"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			return NewToken(tNUMBER, boolInt(vm.varType(args["variable"]) == "Object")), nil
+		},
+	}
+	stdFunctions["isptr"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "variable"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			//Window handles are pointers too
+			return NewToken(tNUMBER, boolInt(args["variable"].Type == tPTR || args["variable"].Type == tHWND)), nil
+		},
+	}
+	stdFunctions["isstring"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "variable"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			return NewToken(tNUMBER, boolInt(args["variable"].Type == tSTRING)), nil
+		},
+	}
 	stdFunctions["vargettype"] = &Function{
 		Args: []*FunctionArg{
 			&FunctionArg{Name: "expression", DefaultValue: NewToken(tDEFAULT, "")},
 		},
 		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
-			varType := string(args["expression"].Type)
-			switch args["expression"].Type {
-			case tBINARY:
-				varType = "Binary"
-			case tNULL:
-				varType = "Keyword"
-			case tBOOLEAN:
-				varType = "Bool"
-			case tSTRING:
-				varType = "String"
-			case tDEFAULT:
-				varType = "Keyword"
+			return NewToken(tSTRING, vm.varType(args["expression"])), nil
+		},
+	}
+
+	//String management
+	stringFormatArgs := []*FunctionArg{
+		&FunctionArg{Name: "format"},
+	}
+	for i := 1; i <= 32; i++ {
+		stringFormatArgs = append(stringFormatArgs, &FunctionArg{Name: fmt.Sprintf("var%d", i), DefaultValue: NewToken(tSTRING, "")})
+	}
+	stdFunctions["stringformat"] = &Function{
+		Args: stringFormatArgs,
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			vars := make([]*Token, 0)
+			for i := 1; i <= 32; i++ {
+				vars = append(vars, args[fmt.Sprintf("var%d", i)])
 			}
-			return NewToken(tSTRING, strings.Title(varType)), nil
+			return NewToken(tSTRING, stringFormat(args["format"].String(), vars)), nil
 		},
 	}
 
@@ -104,6 +314,197 @@ func init() {
 	}
 }
 
+//stringFormat formats vars using the printf style specifiers supported by AutoIt
+//Format: %[flags][width][.precision]type
+//Flags: - + 0 # (space)
+//Types: d i u o x X e E f g G s
+func stringFormat(format string, vars []*Token) string {
+	out := ""
+	varPos := 0
+	nextVar := func() *Token {
+		if varPos >= len(vars) || vars[varPos] == nil {
+			varPos++
+			return NewToken(tSTRING, "")
+		}
+		varPos++
+		return vars[varPos-1]
+	}
+
+	for i := 0; i < len(format); i++ {
+		switch format[i] {
+		case '\\':
+			if i+1 < len(format) {
+				escaped := true
+				switch format[i+1] {
+				case 'n':
+					out += "\n"
+				case 'r':
+					out += "\r"
+				case 't':
+					out += "\t"
+				case '\\':
+					out += "\\"
+				default:
+					escaped = false
+				}
+				if escaped {
+					i++
+					continue
+				}
+			}
+			out += "\\"
+		case '%':
+			if i+1 < len(format) && format[i+1] == '%' {
+				out += "%"
+				i++
+				continue
+			}
+
+			//Read the specification up to and including the type
+			start := i
+			j := i + 1
+			flags := ""
+			for ; j < len(format) && strings.IndexByte("-+ 0#", format[j]) > -1; j++ {
+				if strings.IndexByte(flags, format[j]) == -1 {
+					flags += string(format[j])
+				}
+			}
+			width := ""
+			for ; j < len(format) && format[j] >= '0' && format[j] <= '9'; j++ {
+				width += string(format[j])
+			}
+			precision := -1
+			if j < len(format) && format[j] == '.' {
+				precision = 0
+				for j++; j < len(format) && format[j] >= '0' && format[j] <= '9'; j++ {
+					precision = (precision * 10) + int(format[j]-'0')
+				}
+			}
+			if j >= len(format) || strings.IndexByte("diuoxXeEfgGs", format[j]) == -1 {
+				//Not a valid specification, so write it literally
+				out += format[start:j]
+				i = j - 1
+				continue
+			}
+			verb := format[j]
+			i = j
+
+			spec := "%" + flags + width
+			if precision > -1 {
+				spec += fmt.Sprintf(".%d", precision)
+			}
+
+			value := nextVar()
+			switch verb {
+			case 'd', 'i':
+				out += fmt.Sprintf(spec+"d", intValue(value))
+			case 'u', 'o', 'x', 'X':
+				number := formatUint(intValue(value))
+				if number == 0 {
+					//C omits the base prefix for zero
+					spec = strings.Replace(spec, "#", "", 1)
+				}
+				switch verb {
+				case 'u':
+					out += fmt.Sprintf(spec+"d", number)
+				default:
+					out += fmt.Sprintf(spec+string(verb), number)
+				}
+			case 'e', 'E', 'f':
+				out += fmt.Sprintf(spec+string(verb), value.Float64())
+			case 'g', 'G':
+				//C defaults to 6 significant digits where Go uses the smallest necessary
+				if precision == -1 {
+					spec += ".6"
+				} else if precision == 0 {
+					spec = strings.TrimSuffix(spec, ".0") + ".1"
+				}
+				out += fmt.Sprintf(spec+string(verb), value.Float64())
+			case 's':
+				out += fmt.Sprintf(spec+"s", value.String())
+			}
+		default:
+			out += string(format[i])
+		}
+	}
+	return out
+}
+
+//intValue converts a token to an integer, truncating any decimal point value
+func intValue(t *Token) int64 {
+	switch t.Type {
+	case tSTRING:
+		_, number, _ := parseNumber(t.Data)
+		return number
+	case tBOOLEAN:
+		if t.Bool() {
+			return 1
+		}
+		return 0
+	case tPTR, tHWND:
+		return int64(t.Uint())
+	case tBINARY:
+		//Hex literals are numbers when used in numeric expressions
+		number, err := strconv.ParseUint(t.Data, 16, 64)
+		if err != nil {
+			return 0
+		}
+		if len(t.Data) <= 8 {
+			return int64(int32(number))
+		}
+		return int64(number)
+	}
+	if number := t.Int64(); number != 0 {
+		return number
+	}
+	return int64(t.Float64())
+}
+
+//formatUint reinterprets a negative integer as unsigned using its AutoIt width
+func formatUint(number int64) uint64 {
+	if number < 0 && number >= math.MinInt32 {
+		return uint64(uint32(number))
+	}
+	return uint64(number)
+}
+
+//varType returns the AutoIt type name of a token, as returned by VarGetType
+func (vm *AutoItVM) varType(t *Token) string {
+	switch t.Type {
+	case tNUMBER, tDOUBLE:
+		number := t.Float64()
+		if t.Type == tDOUBLE || !isWholeNumber(number) {
+			return "Double"
+		}
+		if !isInt32(intValue(t)) {
+			return "Int64"
+		}
+		return "Int32"
+	case tNULL:
+		return "Keyword"
+	case tHANDLE:
+		switch vm.GetHandle(t.Handle()).(type) {
+		case []*Token:
+			return "Array"
+		case map[string]*Token:
+			return "Map"
+		case *dllStruct:
+			return "DLLStruct"
+		case Object:
+			return "Object"
+		}
+	}
+	return strings.Title(string(t.Type))
+}
+
+//boolInt returns 1 if b is true, otherwise 0
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
 //Impl based on https://stackoverflow.com/a/35164011
 //Determines whether a given number has a decimal point value
 func isWholeNumber(num float64) bool {
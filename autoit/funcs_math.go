@@ -0,0 +1,324 @@
+package autoit
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+func init() {
+	//Math
+	stdFunctions["abs"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "expression"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			return NewDouble(math.Abs(args["expression"].Float64())), nil
+		},
+	}
+	stdFunctions["ceiling"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "expression"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			return NewDouble(math.Ceil(args["expression"].Float64())), nil
+		},
+	}
+	stdFunctions["exp"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "expression"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			return NewDouble(math.Exp(args["expression"].Float64())), nil
+		},
+	}
+	stdFunctions["floor"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "expression"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			return NewDouble(math.Floor(args["expression"].Float64())), nil
+		},
+	}
+	stdFunctions["int"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "expression"},
+			&FunctionArg{Name: "flag", DefaultValue: NewToken(tNUMBER, 0)},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			number := intValue(args["expression"])
+			if args["flag"].Int() == 1 {
+				number = int64(int32(number))
+			}
+			return NewToken(tNUMBER, number), nil
+		},
+	}
+	stdFunctions["log"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "expression"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			return NewDouble(math.Log(args["expression"].Float64())), nil
+		},
+	}
+	stdFunctions["mod"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "dividend"},
+			&FunctionArg{Name: "divisor"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			return NewDouble(math.Mod(args["dividend"].Float64(), args["divisor"].Float64())), nil
+		},
+	}
+	stdFunctions["round"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "expression"},
+			&FunctionArg{Name: "decimalplaces", DefaultValue: NewToken(tNUMBER, 0)},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			places := math.Pow(10, float64(args["decimalplaces"].Int()))
+			return NewDouble(math.Round(args["expression"].Float64()*places)/places), nil
+		},
+	}
+	stdFunctions["sqrt"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "expression"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			return NewDouble(math.Sqrt(args["expression"].Float64())), nil
+		},
+	}
+
+	//Trigonometry
+	stdFunctions["acos"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "expression"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			return NewDouble(math.Acos(args["expression"].Float64())), nil
+		},
+	}
+	stdFunctions["asin"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "expression"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			return NewDouble(math.Asin(args["expression"].Float64())), nil
+		},
+	}
+	stdFunctions["atan"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "expression"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			return NewDouble(math.Atan(args["expression"].Float64())), nil
+		},
+	}
+	stdFunctions["cos"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "expression"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			return NewDouble(math.Cos(args["expression"].Float64())), nil
+		},
+	}
+	stdFunctions["sin"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "expression"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			return NewDouble(math.Sin(args["expression"].Float64())), nil
+		},
+	}
+	stdFunctions["tan"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "expression"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			return NewDouble(math.Tan(args["expression"].Float64())), nil
+		},
+	}
+
+	//Random numbers
+	stdFunctions["random"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "min", DefaultValue: NewToken(tNUMBER, 0)},
+			&FunctionArg{Name: "max", DefaultValue: NewToken(tNUMBER, 1)},
+			&FunctionArg{Name: "flag", DefaultValue: NewToken(tNUMBER, 0)},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			min := args["min"].Float64()
+			max := args["max"].Float64()
+			if min > max {
+				vm.SetError(1)
+				return NewToken(tNUMBER, 0), nil
+			}
+			if args["flag"].Int() == 1 {
+				//Integers include the max value
+				iMin := int64(math.Ceil(min))
+				iMax := int64(math.Floor(max))
+				if iMin > iMax {
+					vm.SetError(1)
+					return NewToken(tNUMBER, 0), nil
+				}
+				return NewToken(tNUMBER, iMin+vm.random.Int63n(iMax-iMin+1)), nil
+			}
+			return NewDouble(min+(vm.random.Float64()*(max-min))), nil
+		},
+	}
+	stdFunctions["srandom"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "seed"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			vm.random.Seed(intValue(args["seed"]))
+			return NewToken(tNUMBER, 1), nil
+		},
+	}
+
+	//Bitwise operations
+	bitArgs := []*FunctionArg{
+		&FunctionArg{Name: "value1"},
+		&FunctionArg{Name: "value2"},
+	}
+	for i := 3; i <= 255; i++ {
+		bitArgs = append(bitArgs, &FunctionArg{Name: fmt.Sprintf("value%d", i), DefaultValue: NewToken(tDEFAULT, "")})
+	}
+	stdFunctions["bitand"] = &Function{
+		Args: bitArgs,
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			values, is64 := bitValues(args)
+			result := values[0]
+			for _, value := range values[1:] {
+				result &= value
+			}
+			return bitResult(result, is64), nil
+		},
+	}
+	stdFunctions["bitor"] = &Function{
+		Args: bitArgs,
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			values, is64 := bitValues(args)
+			result := values[0]
+			for _, value := range values[1:] {
+				result |= value
+			}
+			return bitResult(result, is64), nil
+		},
+	}
+	stdFunctions["bitxor"] = &Function{
+		Args: bitArgs,
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			values, is64 := bitValues(args)
+			result := values[0]
+			for _, value := range values[1:] {
+				result ^= value
+			}
+			return bitResult(result, is64), nil
+		},
+	}
+	stdFunctions["bitnot"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "value"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			value := intValue(args["value"])
+			return bitResult(^value, !isInt32(value)), nil
+		},
+	}
+	stdFunctions["bitshift"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "value"},
+			&FunctionArg{Name: "shift"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			value := intValue(args["value"])
+			shift := args["shift"].Int()
+			if !isInt32(value) {
+				if shift >= 0 {
+					return NewToken(tNUMBER, int64(uint64(value)>>uint(shift))), nil
+				}
+				return NewToken(tNUMBER, value<<uint(-shift)), nil
+			}
+
+			//Shifting right is unsigned, so the sign bit isn't carried
+			if shift >= 0 {
+				return bitResult(int64(uint32(value)>>uint(shift)), false), nil
+			}
+			return bitResult(int64(uint32(value)<<uint(-shift)), false), nil
+		},
+	}
+	stdFunctions["bitrotate"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "value"},
+			&FunctionArg{Name: "shift", DefaultValue: NewToken(tNUMBER, 1)},
+			&FunctionArg{Name: "size", DefaultValue: NewToken(tSTRING, "W")},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			bits := uint(0)
+			switch strings.ToUpper(args["size"].String()) {
+			case "B":
+				bits = 8
+			case "W":
+				bits = 16
+			case "D":
+				bits = 32
+			case "Q":
+				bits = 64
+			default:
+				vm.SetError(-1)
+				return NewToken(tNUMBER, 0), nil
+			}
+
+			value := uint64(intValue(args["value"]))
+			mask := uint64(math.MaxUint64)
+			if bits < 64 {
+				mask = (1 << bits) - 1
+			}
+			value &= mask
+
+			//Positive shifts rotate left, negative shifts rotate right
+			shift := args["shift"].Int() % int(bits)
+			if shift < 0 {
+				shift += int(bits)
+			}
+			rotated := ((value << uint(shift)) | (value >> (bits - uint(shift)))) & mask
+
+			if bits == 32 {
+				return NewToken(tNUMBER, int64(int32(rotated))), nil
+			}
+			return NewToken(tNUMBER, int64(rotated)), nil
+		},
+	}
+}
+
+//bitValues returns the values given to a bitwise function and whether any require 64-bit operations
+func bitValues(args map[string]*Token) ([]int64, bool) {
+	values := make([]int64, 0)
+	is64 := false
+	for i := 1; i <= 255; i++ {
+		arg := args[fmt.Sprintf("value%d", i)]
+		if arg == nil || arg.Type == tDEFAULT {
+			break
+		}
+		value := intValue(arg)
+		if !isInt32(value) {
+			is64 = true
+		}
+		values = append(values, value)
+	}
+	return values, is64
+}
+
+//bitResult returns the result of a bitwise operation, truncated to 32 bits unless 64-bit operations were used
+func bitResult(result int64, is64 bool) *Token {
+	if !is64 {
+		result = int64(int32(result))
+	}
+	return NewToken(tNUMBER, result)
+}
+
+//isInt32 determines whether a number fits in a signed 32-bit integer
+func isInt32(number int64) bool {
+	return number >= math.MinInt32 && number <= math.MaxInt32
+}
@@ -1,27 +1,427 @@
 package autoit
 
 import (
-	"io/ioutil"
-	"net/http"
+	"io"
+	"net"
+	"time"
 )
 
+//socketPollTime is how long socket functions wait for activity, so they return almost immediately like AutoIt's
+const socketPollTime = time.Millisecond
+
+//NetInterfaceProvider lists the network addresses of the machine in the order of their interfaces
+type NetInterfaceProvider interface {
+	Addrs() ([]net.Addr, error)
+}
+
+//systemNetInterfaces lists the addresses of the network interfaces that are up
+type systemNetInterfaces struct{}
+
 func init() {
-	stdFunctions["inetread"] = &Function{
+	//TCP
+	stdFunctions["tcpstartup"] = &Function{
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			//Sockets don't need any setup outside of Windows, and Go handles it there
+			return NewToken(tNUMBER, 1), nil
+		},
+	}
+	stdFunctions["tcpshutdown"] = &Function{
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			vm.closeSockets()
+			return NewToken(tNUMBER, 1), nil
+		},
+	}
+	stdFunctions["tcplisten"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "ipaddr"},
+			&FunctionArg{Name: "port"},
+			&FunctionArg{Name: "maxpendingconnection", DefaultValue: NewToken(tDEFAULT, "")}, //The system decides the backlog
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			listener, err := net.Listen("tcp", socketAddress(args["ipaddr"], args["port"]))
+			if err != nil {
+				vm.SetError(1)
+				return NewToken(tNUMBER, -1), nil
+			}
+			return vm.AddHandle(listener), nil
+		},
+	}
+	stdFunctions["tcpaccept"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "mainsocket"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			listener, ok := vm.GetHandle(args["mainsocket"].Handle()).(*net.TCPListener)
+			if !ok {
+				vm.SetError(-1)
+				return NewToken(tNUMBER, -1), nil
+			}
+
+			//Accepting doesn't block, so scripts poll until a client connects
+			listener.SetDeadline(time.Now().Add(socketPollTime))
+			conn, err := listener.Accept()
+			if err != nil {
+				if !isTimeout(err) {
+					vm.SetError(1)
+				}
+				return NewToken(tNUMBER, -1), nil
+			}
+			return vm.AddHandle(conn), nil
+		},
+	}
+	stdFunctions["tcpconnect"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "ipaddr"},
+			&FunctionArg{Name: "port"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			conn, err := (&net.Dialer{}).DialContext(vm.context(), "tcp", socketAddress(args["ipaddr"], args["port"]))
+			if err != nil {
+				vm.SetError(1)
+				return NewToken(tNUMBER, -1), nil
+			}
+			return vm.AddHandle(conn), nil
+		},
+	}
+	stdFunctions["tcpsend"] = &Function{
 		Args: []*FunctionArg{
-			&FunctionArg{Name: "url"},
-			&FunctionArg{Name: "options", DefaultValue: NewToken(tNUMBER, 0)},
+			&FunctionArg{Name: "mainsocket"},
+			&FunctionArg{Name: "data"},
 		},
 		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
-			resp, err := http.Get(args["url"].String())
+			conn, ok := vm.GetHandle(args["mainsocket"].Handle()).(*net.TCPConn)
+			if !ok {
+				vm.SetError(-1)
+				return NewToken(tNUMBER, 0), nil
+			}
+			sent, err := conn.Write(args["data"].Bytes())
 			if err != nil {
-				return nil, err
+				vm.SetError(1)
+			}
+			return NewToken(tNUMBER, sent), nil
+		},
+	}
+	stdFunctions["tcprecv"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "mainsocket"},
+			&FunctionArg{Name: "maxlen"},
+			&FunctionArg{Name: "flag", DefaultValue: NewToken(tNUMBER, 0)},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			binary := args["flag"].Int() == 1
+			conn, ok := vm.GetHandle(args["mainsocket"].Handle()).(*net.TCPConn)
+			if !ok || args["maxlen"].Int() < 1 {
+				vm.SetError(-1)
+				return socketData(nil, binary), nil
+			}
+
+			//Receiving doesn't block, so nothing is returned until data arrives
+			data := make([]byte, args["maxlen"].Int())
+			conn.SetReadDeadline(time.Now().Add(socketPollTime))
+			received, err := conn.Read(data)
+			if err != nil && !isTimeout(err) {
+				if err == io.EOF {
+					//The other side closed the connection
+					vm.SetError(-2)
+					vm.SetExtended(1)
+				} else {
+					vm.SetError(1)
+				}
+			}
+			return socketData(data[:received], binary), nil
+		},
+	}
+	stdFunctions["tcpclosesocket"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "socket"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			socket, ok := vm.GetHandle(args["socket"].Handle()).(io.Closer)
+			if !ok {
+				vm.SetError(-1)
+				return NewToken(tNUMBER, 0), nil
 			}
-			defer resp.Body.Close()
-			data, err := ioutil.ReadAll(resp.Body)
+			socket.Close()
+			vm.DestroyHandle(args["socket"].Handle())
+			return NewToken(tNUMBER, 1), nil
+		},
+	}
+	stdFunctions["tcpnametoip"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "name"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			ips, err := net.DefaultResolver.LookupIPAddr(vm.context(), args["name"].String())
 			if err != nil {
-				return nil, err
+				vm.SetError(1)
+				return NewToken(tSTRING, ""), nil
+			}
+			for _, ip := range ips {
+				if ip4 := ip.IP.To4(); ip4 != nil {
+					return NewToken(tSTRING, ip4.String()), nil
+				}
 			}
-			return NewToken(tBINARY, data), nil
+			vm.SetError(1)
+			return NewToken(tSTRING, ""), nil
 		},
 	}
-}
\ No newline at end of file
+
+	//UDP
+	stdFunctions["udpstartup"] = stdFunctions["tcpstartup"]
+	stdFunctions["udpshutdown"] = stdFunctions["tcpshutdown"]
+	stdFunctions["udpbind"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "ipaddr"},
+			&FunctionArg{Name: "port"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			address, err := vm.resolveUDPAddr(args["ipaddr"], args["port"])
+			if err != nil {
+				vm.SetError(1)
+				return NewToken(tNUMBER, -1), nil
+			}
+			conn, err := net.ListenUDP("udp", address)
+			if err != nil {
+				vm.SetError(1)
+				return NewToken(tNUMBER, -1), nil
+			}
+			return vm.udpSocket(conn, args["ipaddr"], args["port"]), nil
+		},
+	}
+	stdFunctions["udpopen"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "ipaddr"},
+			&FunctionArg{Name: "port"},
+			&FunctionArg{Name: "flag", DefaultValue: NewToken(tNUMBER, 0)}, //Broadcasting is always allowed
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			address, err := vm.resolveUDPAddr(args["ipaddr"], args["port"])
+			if err != nil {
+				vm.SetError(1)
+				return NewToken(tNUMBER, -1), nil
+			}
+			conn, err := net.DialUDP("udp", nil, address)
+			if err != nil {
+				vm.SetError(1)
+				return NewToken(tNUMBER, -1), nil
+			}
+			return vm.udpSocket(conn, args["ipaddr"], args["port"]), nil
+		},
+	}
+	stdFunctions["udpsend"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "socketarray"},
+			&FunctionArg{Name: "data"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			conn, ok := vm.GetHandle(vm.socketHandle(args["socketarray"])).(*net.UDPConn)
+			if !ok {
+				vm.SetError(-1)
+				return NewToken(tNUMBER, 0), nil
+			}
+			var sent int
+			var err error
+			if conn.RemoteAddr() != nil {
+				sent, err = conn.Write(args["data"].Bytes())
+			} else {
+				//Bound sockets send to the IP address and port of the array, which servers set to reply to a sender
+				values := vm.GetArray(args["socketarray"])
+				if len(values) < 4 || values[2] == nil || values[3] == nil {
+					vm.SetError(-1)
+					return NewToken(tNUMBER, 0), nil
+				}
+				var address *net.UDPAddr
+				address, err = vm.resolveUDPAddr(values[2], values[3])
+				if err == nil {
+					sent, err = conn.WriteToUDP(args["data"].Bytes(), address)
+				}
+			}
+			if err != nil {
+				vm.SetError(1)
+			}
+			return NewToken(tNUMBER, sent), nil
+		},
+	}
+	stdFunctions["udprecv"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "socketarray"},
+			&FunctionArg{Name: "maxlen"},
+			&FunctionArg{Name: "flag", DefaultValue: NewToken(tNUMBER, 0)},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			binary := args["flag"].Int()&1 != 0
+			withSender := args["flag"].Int()&2 != 0
+			conn, ok := vm.GetHandle(vm.socketHandle(args["socketarray"])).(*net.UDPConn)
+			if !ok || args["maxlen"].Int() < 1 {
+				vm.SetError(-1)
+				return socketData(nil, binary), nil
+			}
+
+			data := make([]byte, args["maxlen"].Int())
+			conn.SetReadDeadline(time.Now().Add(socketPollTime))
+			received, sender, err := conn.ReadFromUDP(data)
+			if err != nil {
+				if !isTimeout(err) {
+					vm.SetError(1)
+				}
+				return socketData(nil, binary), nil
+			}
+
+			//The sender is returned alongside the data when asked for
+			if withSender {
+				return vm.NewArray([]*Token{socketData(data[:received], binary), NewToken(tSTRING, sender.IP.String()), NewToken(tNUMBER, sender.Port)}), nil
+			}
+			return socketData(data[:received], binary), nil
+		},
+	}
+	stdFunctions["udpclosesocket"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "socketarray"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			handle := vm.socketHandle(args["socketarray"])
+			conn, ok := vm.GetHandle(handle).(*net.UDPConn)
+			if !ok {
+				vm.SetError(-1)
+				return NewToken(tNUMBER, 0), nil
+			}
+			conn.Close()
+			vm.DestroyHandle(handle)
+			return NewToken(tNUMBER, 1), nil
+		},
+	}
+}
+
+//socketAddress joins an IP address and port into an address for dialing or listening
+func socketAddress(ip, port *Token) string {
+	return net.JoinHostPort(ip.String(), port.String())
+}
+
+//resolveUDPAddr resolves an IP address or host name and port into a UDP address, preferring IPv4 like
+//net.ResolveUDPAddr but giving up once the script is cancelled
+func (vm *AutoItVM) resolveUDPAddr(host, port *Token) (*net.UDPAddr, error) {
+	address := &net.UDPAddr{Port: port.Int()}
+	if host.String() == "" {
+		//Binding to every interface
+		return address, nil
+	}
+	ips, err := net.DefaultResolver.LookupIPAddr(vm.context(), host.String())
+	if err != nil {
+		return nil, err
+	}
+	if len(ips) == 0 {
+		return nil, &net.DNSError{Err: "no such host", Name: host.String(), IsNotFound: true}
+	}
+	address.IP, address.Zone = ips[0].IP, ips[0].Zone
+	for _, ip := range ips {
+		if ip.IP.To4() != nil {
+			address.IP, address.Zone = ip.IP, ip.Zone
+			break
+		}
+	}
+	return address, nil
+}
+
+//socketData returns received data as binary or as a string
+func socketData(data []byte, binary bool) *Token {
+	if binary {
+		return NewToken(tBINARY, data)
+	}
+	return NewToken(tSTRING, string(data))
+}
+
+//isTimeout determines whether an error came from a deadline passing
+func isTimeout(err error) bool {
+	netErr, ok := err.(net.Error)
+	return ok && netErr.Timeout()
+}
+
+//udpSocket returns a UDP socket as the array AutoIt uses, holding the socket, IP address and port from index 1
+func (vm *AutoItVM) udpSocket(conn *net.UDPConn, ip, port *Token) *Token {
+	return vm.NewArray([]*Token{NewToken(tNUMBER, 0), vm.AddHandle(conn), NewToken(tSTRING, ip.String()), NewToken(tNUMBER, port.Int())})
+}
+
+//socketHandle returns the handle of a UDP socket given either the socket array or the socket itself
+func (vm *AutoItVM) socketHandle(socket *Token) string {
+	if values := vm.GetArray(socket); len(values) > 1 && values[1] != nil {
+		return values[1].Handle()
+	}
+	return socket.Handle()
+}
+
+//closeSockets closes every socket held by this VM
+func (vm *AutoItVM) closeSockets() {
+	for handleId, handle := range vm.handles {
+		switch socket := handle.(type) {
+		case net.Listener:
+			socket.Close()
+		case net.Conn:
+			socket.Close()
+		default:
+			continue
+		}
+		vm.DestroyHandle(handleId)
+	}
+}
+
+//Addrs returns the addresses of every network interface that is up
+func (systemNetInterfaces) Addrs() ([]net.Addr, error) {
+	netInterfaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	addresses := make([]net.Addr, 0)
+	for _, netInterface := range netInterfaces {
+		if netInterface.Flags&net.FlagUp == 0 {
+			continue
+		}
+		interfaceAddresses, err := netInterface.Addrs()
+		if err != nil {
+			continue
+		}
+		addresses = append(addresses, interfaceAddresses...)
+	}
+	return addresses, nil
+}
+
+//netInterfaces returns the provider set on this VM or the scopes above it, falling back to the system interfaces
+func (vm *AutoItVM) netInterfaces() NetInterfaceProvider {
+	if vm.NetInterfaces != nil {
+		return vm.NetInterfaces
+	}
+	if vm.parentScope != nil {
+		return vm.parentScope.netInterfaces()
+	}
+	return systemNetInterfaces{}
+}
+
+//ipAddress returns the nth non-loopback IPv4 address as @IPAddress1-4 do, with @IPAddress1 falling back to the
+//loopback address and the others to 0.0.0.0
+func (vm *AutoItVM) ipAddress(n int) string {
+	fallback := "0.0.0.0"
+	if n == 1 {
+		fallback = "127.0.0.1"
+	}
+
+	addresses, err := vm.netInterfaces().Addrs()
+	if err != nil {
+		return fallback
+	}
+	for _, address := range addresses {
+		var ip net.IP
+		switch v := address.(type) {
+		case *net.IPNet:
+			ip = v.IP
+		case *net.IPAddr:
+			ip = v.IP
+		}
+		if ip = ip.To4(); ip == nil || ip.IsLoopback() {
+			continue
+		}
+		n--
+		if n == 0 {
+			return ip.String()
+		}
+	}
+	return fallback
+}
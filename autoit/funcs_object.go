@@ -0,0 +1,168 @@
+package autoit
+
+import (
+	"fmt"
+	"strings"
+)
+
+//Object is a value scripts use with dot notation, such as the objects created by ObjCreate
+type Object interface {
+	//ObjectName returns the name reported by ObjName
+	ObjectName() string
+	//Get returns the value of a property
+	Get(vm *AutoItVM, name string) (*Token, error)
+	//Set sets the value of a property, which may be indexed by arguments such as $oDict.Item("key") = 1
+	Set(vm *AutoItVM, name string, args []*Token, value *Token) error
+	//Call calls a method, which is also used for properties that take arguments
+	Call(vm *AutoItVM, name string, args []*Token) (*Token, error)
+}
+
+//ObjectConstructor creates a new object for ObjCreate
+type ObjectConstructor func(vm *AutoItVM) (Object, error)
+
+//objClasses holds the object classes available to ObjCreate, keyed by lowercase ProgID
+var objClasses = make(map[string]ObjectConstructor)
+
+//RegisterObjectClass makes an object class available to ObjCreate under the given ProgID
+func RegisterObjectClass(progID string, constructor ObjectConstructor) {
+	objClasses[strings.ToLower(progID)] = constructor
+}
+
+//errUnknownMember is returned when an object doesn't have the requested member
+func errUnknownMember(object Object, name string) error {
+	return fmt.Errorf("unknown name %s for object %s", name, object.ObjectName())
+}
+
+//errMemberArgs is returned when an object member is called with the wrong number of arguments
+func errMemberArgs(object Object, name string) error {
+	return fmt.Errorf("wrong number of arguments for %s.%s", object.ObjectName(), name)
+}
+
+//objectArg returns an argument of a member call, or def if it wasn't given
+func objectArg(args []*Token, index int, def *Token) *Token {
+	if index >= len(args) || args[index].Type == tDEFAULT {
+		return def
+	}
+	return args[index]
+}
+
+func init() {
+	//Objects
+	stdFunctions["objcreate"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "classname"},
+			&FunctionArg{Name: "servername", DefaultValue: NewToken(tSTRING, "")},
+			&FunctionArg{Name: "username", DefaultValue: NewToken(tSTRING, "")},
+			&FunctionArg{Name: "password", DefaultValue: NewToken(tSTRING, "")},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			constructor, exists := objClasses[strings.ToLower(args["classname"].String())]
+			if !exists || args["servername"].String() != "" {
+				vm.SetError(1)
+				return NewToken(tNUMBER, 0), nil
+			}
+			object, err := constructor(vm)
+			if err != nil {
+				vm.SetError(1)
+				return NewToken(tNUMBER, 0), nil
+			}
+			return vm.AddHandle(object), nil
+		},
+	}
+	stdFunctions["objname"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "object"},
+			&FunctionArg{Name: "flag", DefaultValue: NewToken(tNUMBER, 1)},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			object := vm.getObject(args["object"])
+			if object == nil || args["flag"].Int() != 1 {
+				vm.SetError(1)
+				return NewToken(tSTRING, ""), nil
+			}
+			return NewToken(tSTRING, object.ObjectName()), nil
+		},
+	}
+}
+
+//getObject returns the object held by a token, or nil if it isn't an object
+func (vm *AutoItVM) getObject(t *Token) Object {
+	if t == nil || t.Type != tHANDLE {
+		return nil
+	}
+	object, _ := vm.GetHandle(t.Handle()).(Object)
+	return object
+}
+
+//memberCall returns the name of an object member and evaluates the arguments it's called with
+func (vm *AutoItVM) memberCall(tMember *Token) (string, []*Token, error) {
+	if tMember.Type == tPROPERTY {
+		return tMember.String(), nil, nil
+	}
+	methodCall, ok := vm.GetHandle(tMember.String()).(*FunctionCall)
+	if !ok {
+		return "", nil, vm.Error("undefined method call attempt, did preprocessing fail?: %v", *tMember)
+	}
+
+	args := make([]*Token, len(methodCall.Block))
+	for i, block := range methodCall.Block {
+		tValue, _, err := NewEvaluator(vm, block).Eval(true)
+		if err != nil {
+			return "", nil, err
+		}
+		args[i] = tValue
+	}
+	return methodCall.Name, args, nil
+}
+
+//objectMember reads a property or calls a method of an object
+func (vm *AutoItVM) objectMember(tObject, tMember *Token) (*Token, error) {
+	object := vm.getObject(tObject)
+	if object == nil {
+		return nil, vm.Error("variable must be of type Object to access member %s", tMember.String())
+	}
+	name, args, err := vm.memberCall(tMember)
+	if err != nil {
+		return nil, err
+	}
+
+	var tValue *Token
+	if tMember.Type == tPROPERTY {
+		tValue, err = object.Get(vm, name)
+	} else {
+		tValue, err = object.Call(vm, name, args)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if tValue == nil {
+		return NewToken(tSTRING, ""), nil
+	}
+	return tValue, nil
+}
+
+//callObjectMember calls a method of an object used as a statement, where the parentheses are optional
+func (vm *AutoItVM) callObjectMember(tObject, tMember *Token) (*Token, error) {
+	object := vm.getObject(tObject)
+	if object == nil {
+		return nil, vm.Error("variable must be of type Object to call member %s", tMember.String())
+	}
+	name, args, err := vm.memberCall(tMember)
+	if err != nil {
+		return nil, err
+	}
+	return object.Call(vm, name, args)
+}
+
+//setObjectMember assigns a value to a property of an object
+func (vm *AutoItVM) setObjectMember(tObject, tMember, tValue *Token) error {
+	object := vm.getObject(tObject)
+	if object == nil {
+		return vm.Error("variable must be of type Object to set member %s", tMember.String())
+	}
+	name, args, err := vm.memberCall(tMember)
+	if err != nil {
+		return err
+	}
+	return object.Set(vm, name, args, tValue)
+}
@@ -0,0 +1,525 @@
+package autoit
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//Stream options used by Run
+const (
+	stdinChild         = 1
+	stdoutChild        = 2
+	stderrChild        = 4
+	stderrMerged       = 8
+	stdioInheritParent = 16
+)
+
+//childProcess holds a process started by Run and the streams redirected from it
+type childProcess struct {
+	cmd            *exec.Cmd
+	stdin          io.WriteCloser
+	stdout, stderr *processStream
+	done           chan struct{} //Closed once the process exits
+	exitCode       int
+}
+
+//processStream buffers the output of a child process until the script reads it
+type processStream struct {
+	mutex  sync.Mutex
+	data   []byte
+	closed bool //Set once the process exits or the script closes the stream
+}
+
+func init() {
+	//Process execution
+	stdFunctions["run"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "program"},
+			&FunctionArg{Name: "workingdir", DefaultValue: NewToken(tSTRING, "")},
+			&FunctionArg{Name: "showflag", DefaultValue: NewToken(tNUMBER, 1)}, //Windows don't exist on every platform, so this is ignored
+			&FunctionArg{Name: "optflag", DefaultValue: NewToken(tNUMBER, 0)},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			cmd, err := shellCommand(args["program"].String())
+			if err != nil {
+				vm.SetError(1)
+				return NewToken(tNUMBER, 0), nil
+			}
+			cmd.Dir = args["workingdir"].String()
+			process, err := vm.startProcess(cmd, args["optflag"].Int())
+			if err != nil {
+				vm.SetError(1)
+				return NewToken(tNUMBER, 0), nil
+			}
+			return NewToken(tNUMBER, process.cmd.Process.Pid), nil
+		},
+	}
+	stdFunctions["runwait"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "program"},
+			&FunctionArg{Name: "workingdir", DefaultValue: NewToken(tSTRING, "")},
+			&FunctionArg{Name: "showflag", DefaultValue: NewToken(tNUMBER, 1)},
+			&FunctionArg{Name: "optflag", DefaultValue: NewToken(tNUMBER, 0)},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			cmd, err := shellCommand(args["program"].String())
+			if err != nil {
+				vm.SetError(1)
+				return NewToken(tNUMBER, 0), nil
+			}
+			cmd.Dir = args["workingdir"].String()
+			process, err := vm.startProcess(cmd, stdioInheritParent)
+			if err != nil {
+				vm.SetError(1)
+				return NewToken(tNUMBER, 0), nil
+			}
+			select {
+			case <-process.done:
+			case <-vm.context().Done():
+				return nil, vm.cancelled()
+			}
+			return NewToken(tNUMBER, process.exitCode), nil
+		},
+	}
+	stdFunctions["shellexecute"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "filename"},
+			&FunctionArg{Name: "parameters", DefaultValue: NewToken(tSTRING, "")},
+			&FunctionArg{Name: "workingdir", DefaultValue: NewToken(tSTRING, "")},
+			&FunctionArg{Name: "verb", DefaultValue: NewToken(tSTRING, "")},
+			&FunctionArg{Name: "showflag", DefaultValue: NewToken(tNUMBER, 1)},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			cmd, err := shellExecuteCommand(args["filename"].String(), args["parameters"].String(), args["verb"].String())
+			if err != nil {
+				vm.SetError(1)
+				return NewToken(tNUMBER, 0), nil
+			}
+			cmd.Dir = args["workingdir"].String()
+			process, err := vm.startProcess(cmd, stdioInheritParent)
+			if err != nil {
+				vm.SetError(1)
+				return NewToken(tNUMBER, 0), nil
+			}
+			return NewToken(tNUMBER, process.cmd.Process.Pid), nil
+		},
+	}
+	stdFunctions["shellexecutewait"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "filename"},
+			&FunctionArg{Name: "parameters", DefaultValue: NewToken(tSTRING, "")},
+			&FunctionArg{Name: "workingdir", DefaultValue: NewToken(tSTRING, "")},
+			&FunctionArg{Name: "verb", DefaultValue: NewToken(tSTRING, "")},
+			&FunctionArg{Name: "showflag", DefaultValue: NewToken(tNUMBER, 1)},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			cmd, err := shellExecuteCommand(args["filename"].String(), args["parameters"].String(), args["verb"].String())
+			if err != nil {
+				vm.SetError(1)
+				return NewToken(tNUMBER, 0), nil
+			}
+			cmd.Dir = args["workingdir"].String()
+			process, err := vm.startProcess(cmd, stdioInheritParent)
+			if err != nil {
+				vm.SetError(1)
+				return NewToken(tNUMBER, 0), nil
+			}
+			select {
+			case <-process.done:
+			case <-vm.context().Done():
+				return nil, vm.cancelled()
+			}
+			return NewToken(tNUMBER, process.exitCode), nil
+		},
+	}
+	stdFunctions["processwaitclose"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "process"},
+			&FunctionArg{Name: "timeout", DefaultValue: NewToken(tNUMBER, 0)},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			pid := processPID(args["process"])
+			var timeout <-chan time.Time
+			if seconds := args["timeout"].Float64(); seconds > 0 {
+				timeout = time.After(time.Duration(seconds * float64(time.Second)))
+			}
+
+			if process := vm.childProcess(pid); process != nil {
+				select {
+				case <-process.done:
+					vm.SetExtended(process.exitCode)
+					return NewToken(tNUMBER, 1), nil
+				case <-timeout:
+					return NewToken(tNUMBER, 0), nil
+				case <-vm.context().Done():
+					return nil, vm.cancelled()
+				}
+			}
+
+			//Processes we didn't start can only be polled
+			ticker := time.NewTicker(250 * time.Millisecond)
+			defer ticker.Stop()
+			for processRunning(pid) {
+				select {
+				case <-ticker.C:
+				case <-timeout:
+					return NewToken(tNUMBER, 0), nil
+				case <-vm.context().Done():
+					return nil, vm.cancelled()
+				}
+			}
+			return NewToken(tNUMBER, 1), nil
+		},
+	}
+
+	//Process control
+	stdFunctions["processclose"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "process"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			pid := findProcess(args["process"])
+			if pid == 0 {
+				vm.SetError(4)
+				return NewToken(tNUMBER, 0), nil
+			}
+			process, err := os.FindProcess(pid)
+			if err != nil {
+				vm.SetError(1)
+				return NewToken(tNUMBER, 0), nil
+			}
+			if process.Kill() != nil {
+				vm.SetError(3)
+				return NewToken(tNUMBER, 0), nil
+			}
+			return NewToken(tNUMBER, 1), nil
+		},
+	}
+	stdFunctions["processexists"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "process"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			return NewToken(tNUMBER, findProcess(args["process"])), nil
+		},
+	}
+	stdFunctions["processgetstats"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "process", DefaultValue: NewToken(tNUMBER, -1)},
+			&FunctionArg{Name: "type", DefaultValue: NewToken(tNUMBER, 0)},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			pid := os.Getpid()
+			if args["process"].Int() != -1 {
+				pid = findProcess(args["process"])
+			}
+			if pid == 0 {
+				vm.SetError(1)
+				return NewToken(tNUMBER, 0), nil
+			}
+
+			var stats []int64
+			var err error
+			switch args["type"].Int() {
+			case 0:
+				stats, err = processMemoryStats(pid)
+			case 1:
+				stats, err = processIOStats(pid)
+			default:
+				vm.SetError(1)
+				return NewToken(tNUMBER, 0), nil
+			}
+			if err != nil {
+				vm.SetError(1)
+				return NewToken(tNUMBER, 0), nil
+			}
+			values := make([]*Token, 0)
+			for _, stat := range stats {
+				values = append(values, NewToken(tNUMBER, stat))
+			}
+			return vm.NewArray(values), nil
+		},
+	}
+	stdFunctions["processlist"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "name", DefaultValue: NewToken(tDEFAULT, "")},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			processes, err := listProcesses()
+			if err != nil {
+				vm.SetError(1)
+				return NewToken(tNUMBER, 0), nil
+			}
+
+			//Each row holds a name and its PID, with the count in the first row
+			rows := []*Token{nil}
+			for _, process := range processes {
+				if args["name"].Type != tDEFAULT && !strings.EqualFold(process.name, args["name"].String()) {
+					continue
+				}
+				rows = append(rows, vm.NewArray([]*Token{NewToken(tSTRING, process.name), NewToken(tNUMBER, process.pid)}))
+			}
+			rows[0] = vm.NewArray([]*Token{NewToken(tNUMBER, len(rows)-1), NewToken(tSTRING, "")})
+			return vm.NewArray(rows), nil
+		},
+	}
+	stdFunctions["processsetpriority"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "process"},
+			&FunctionArg{Name: "priority"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			priority := args["priority"].Int()
+			if priority < 0 || priority > 5 {
+				vm.SetError(2)
+				return NewToken(tNUMBER, 0), nil
+			}
+			pid := findProcess(args["process"])
+			if pid == 0 || setProcessPriority(pid, priority) != nil {
+				vm.SetError(1)
+				return NewToken(tNUMBER, 0), nil
+			}
+			return NewToken(tNUMBER, 1), nil
+		},
+	}
+	stdFunctions["processwait"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "process"},
+			&FunctionArg{Name: "timeout", DefaultValue: NewToken(tNUMBER, 0)},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			var timeout <-chan time.Time
+			if seconds := args["timeout"].Float64(); seconds > 0 {
+				timeout = time.After(time.Duration(seconds * float64(time.Second)))
+			}
+
+			ticker := time.NewTicker(250 * time.Millisecond)
+			defer ticker.Stop()
+			for {
+				if pid := findProcess(args["process"]); pid != 0 {
+					return NewToken(tNUMBER, pid), nil
+				}
+				select {
+				case <-ticker.C:
+				case <-timeout:
+					return NewToken(tNUMBER, 0), nil
+				case <-vm.context().Done():
+					return nil, vm.cancelled()
+				}
+			}
+		},
+	}
+
+	//Child streams
+	stdFunctions["stdoutread"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "processid"},
+			&FunctionArg{Name: "peek", DefaultValue: NewToken(tBOOLEAN, false)},
+			&FunctionArg{Name: "binary", DefaultValue: NewToken(tBOOLEAN, false)},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			process := vm.childProcess(args["processid"].Int())
+			if process == nil {
+				vm.SetError(1)
+				return NewToken(tSTRING, ""), nil
+			}
+			return vm.readStream(process.stdout, args["peek"].Bool(), args["binary"].Bool()), nil
+		},
+	}
+	stdFunctions["stderrread"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "processid"},
+			&FunctionArg{Name: "peek", DefaultValue: NewToken(tBOOLEAN, false)},
+			&FunctionArg{Name: "binary", DefaultValue: NewToken(tBOOLEAN, false)},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			process := vm.childProcess(args["processid"].Int())
+			if process == nil {
+				vm.SetError(1)
+				return NewToken(tSTRING, ""), nil
+			}
+			return vm.readStream(process.stderr, args["peek"].Bool(), args["binary"].Bool()), nil
+		},
+	}
+	stdFunctions["stdinwrite"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "processid"},
+			&FunctionArg{Name: "data", DefaultValue: NewToken(tDEFAULT, "")},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			process := vm.childProcess(args["processid"].Int())
+			if process == nil || process.stdin == nil {
+				vm.SetError(1)
+				return NewToken(tNUMBER, 0), nil
+			}
+
+			//Writing without data closes the stream so the child sees the end of its input
+			if args["data"].Type == tDEFAULT {
+				process.stdin.Close()
+				return NewToken(tNUMBER, 0), nil
+			}
+			written, err := process.stdin.Write(args["data"].Bytes())
+			if err != nil {
+				vm.SetError(1)
+			}
+			return NewToken(tNUMBER, written), nil
+		},
+	}
+	stdFunctions["stdioclose"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "processid"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			process := vm.childProcess(args["processid"].Int())
+			if process == nil {
+				return NewToken(tNUMBER, 0), nil
+			}
+			if process.stdin != nil {
+				process.stdin.Close()
+			}
+			for _, stream := range []*processStream{process.stdout, process.stderr} {
+				if stream != nil {
+					stream.close(true)
+				}
+			}
+			return NewToken(tNUMBER, 1), nil
+		},
+	}
+}
+
+//startProcess starts a command with the streams requested by the Run options and waits for it in the background
+func (vm *AutoItVM) startProcess(cmd *exec.Cmd, flag int) (*childProcess, error) {
+	process := &childProcess{cmd: cmd, done: make(chan struct{})}
+	if flag&stdinChild != 0 {
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return nil, err
+		}
+		process.stdin = stdin
+	} else if flag&stdioInheritParent != 0 {
+		cmd.Stdin = os.Stdin
+	}
+
+	//Output nobody reads is shown on our console
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if flag&(stdoutChild|stderrMerged) != 0 {
+		process.stdout = &processStream{}
+		cmd.Stdout = process.stdout
+	}
+	if flag&stderrMerged != 0 {
+		cmd.Stderr = process.stdout
+	} else if flag&stderrChild != 0 {
+		process.stderr = &processStream{}
+		cmd.Stderr = process.stderr
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	vm.processes[cmd.Process.Pid] = process
+
+	go func() {
+		cmd.Wait()
+		process.exitCode = cmd.ProcessState.ExitCode()
+		for _, stream := range []*processStream{process.stdout, process.stderr} {
+			if stream != nil {
+				stream.close(false)
+			}
+		}
+		close(process.done)
+	}()
+	return process, nil
+}
+
+//processPID returns the PID given to a process function, looking up the first process with a matching name
+//if given one, or 0 if there isn't one
+func processPID(process *Token) int {
+	if process.Type == tNUMBER || process.Type == tDOUBLE {
+		return process.Int()
+	}
+	if pid, err := strconv.Atoi(process.String()); err == nil {
+		return pid
+	}
+	processes, err := listProcesses()
+	if err != nil {
+		return 0
+	}
+	for _, info := range processes {
+		if strings.EqualFold(info.name, process.String()) {
+			return info.pid
+		}
+	}
+	return 0
+}
+
+//findProcess returns the PID of a running process given its name or PID, or 0 if it isn't running
+func findProcess(process *Token) int {
+	pid := processPID(process)
+	if !processRunning(pid) {
+		return 0
+	}
+	return pid
+}
+
+//childProcess returns a process started by this script or nil if it wasn't
+func (vm *AutoItVM) childProcess(pid int) *childProcess {
+	if process, exists := vm.processes[pid]; exists {
+		return process
+	}
+	if vm.parentScope != nil {
+		return vm.parentScope.childProcess(pid)
+	}
+	return nil
+}
+
+//readStream returns the output buffered from a child stream without blocking, setting @error once the
+//stream has ended and @extended to the number of bytes read
+func (vm *AutoItVM) readStream(stream *processStream, peek, binary bool) *Token {
+	if stream == nil {
+		vm.SetError(1)
+		return NewToken(tSTRING, "")
+	}
+
+	stream.mutex.Lock()
+	data := stream.data
+	if !peek {
+		stream.data = nil
+	}
+	ended := stream.closed && len(data) == 0
+	stream.mutex.Unlock()
+
+	if ended {
+		vm.SetError(1)
+	}
+	vm.SetExtended(len(data))
+	if binary {
+		return NewToken(tBINARY, append([]byte{}, data...))
+	}
+	return NewToken(tSTRING, string(data))
+}
+
+//Write buffers output from the child until the script reads it
+func (s *processStream) Write(data []byte) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !s.closed {
+		s.data = append(s.data, data...)
+	}
+	return len(data), nil
+}
+
+//close marks the end of the stream, optionally discarding anything the script hasn't read
+func (s *processStream) close(discard bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.closed = true
+	if discard {
+		s.data = nil
+	}
+}
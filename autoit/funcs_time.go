@@ -11,7 +11,13 @@ func init() {
 			&FunctionArg{Name: "delay"},
 		},
 		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
-			time.Sleep(time.Millisecond * time.Duration(args["delay"].Int64()))
+			timer := time.NewTimer(time.Millisecond * time.Duration(args["delay"].Int64()))
+			defer timer.Stop()
+			select {
+			case <-timer.C:
+			case <-vm.context().Done():
+				return nil, vm.cancelled()
+			}
 			return nil, nil
 		},
 	}
@@ -0,0 +1,739 @@
+package autoit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Access types used by _WinHttpOpen
+const (
+	winHttpAccessDefaultProxy = 0
+	winHttpAccessNoProxy      = 1
+	winHttpAccessNamedProxy   = 3
+)
+
+//Flags and options used by _WinHttpOpenRequest, _WinHttpAddRequestHeaders and _WinHttpSetOption
+const (
+	winHttpFlagSecure          = 0x00800000
+	winHttpAddReqIfNew         = 0x10000000
+	winHttpAddReqAdd           = 0x20000000
+	winHttpAddReqReplace       = 0x80000000
+	winHttpOptionConnectTimout = 3
+	winHttpOptionSendTimeout   = 5
+	winHttpOptionRecvTimeout   = 6
+	winHttpOptionRespTimeout   = 7
+	winHttpOptionSecurityFlags = 31
+	winHttpOptionUserAgent     = 41
+	winHttpOptionDisable       = 63
+	winHttpOptionRedirect      = 88
+	winHttpDisableCookies      = 1
+	winHttpDisableRedirects    = 2
+	winHttpRedirectNever       = 0
+	winHttpRedirectNoDowngrade = 1
+)
+
+//Information levels used by _WinHttpQueryHeaders
+const (
+	winHttpQueryVersion        = 18
+	winHttpQueryStatusCode     = 19
+	winHttpQueryStatusText     = 20
+	winHttpQueryRawHeaders     = 21
+	winHttpQueryRawHeadersCRLF = 22
+	winHttpQueryCustom         = 65535
+	winHttpQueryFlagNumber     = 0x20000000
+	winHttpQueryFlagRequest    = 0x80000000
+)
+
+//winHttpQueryHeaders maps information levels to the headers they query
+var winHttpQueryHeaders = map[int]string{
+	0: "MIME-Version", 1: "Content-Type", 2: "Content-Transfer-Encoding", 3: "Content-ID",
+	4: "Content-Description", 5: "Content-Length", 6: "Content-Language", 7: "Allow", 8: "Public",
+	9: "Date", 10: "Expires", 11: "Last-Modified", 12: "Message-ID", 13: "URI", 14: "Derived-From",
+	15: "Cost", 16: "Link", 17: "Pragma", 23: "Connection", 24: "Accept", 25: "Accept-Charset",
+	26: "Accept-Encoding", 27: "Accept-Language", 28: "Authorization", 29: "Content-Encoding",
+	30: "Forwarded", 31: "From", 32: "If-Modified-Since", 33: "Location", 34: "Orig-URI", 35: "Referer",
+	36: "Retry-After", 37: "Server", 38: "Title", 39: "User-Agent", 40: "WWW-Authenticate",
+	41: "Proxy-Authenticate", 42: "Accept-Ranges", 43: "Set-Cookie", 44: "Cookie",
+}
+
+//winHttpOptions holds the settings that can be changed on any WinHttp handle, which are inherited by the handles
+//opened from it
+type winHttpOptions struct {
+	userAgent                               string
+	connectTimeout, receiveTimeout          time.Duration
+	responseTimeout                         time.Duration
+	securityFlags, disabled, redirectPolicy int
+}
+
+//winHttpSession is the handle returned by _WinHttpOpen
+type winHttpSession struct {
+	options    winHttpOptions
+	proxy      func(*http.Request) (*url.URL, error)
+	jar        http.CookieJar
+	transports map[winHttpTransportKey]*http.Transport
+}
+
+//winHttpTransportKey holds the options a transport is built with, so requests sharing them share its connections
+type winHttpTransportKey struct {
+	connectTimeout, responseTimeout time.Duration
+	insecure                        bool
+}
+
+//winHttpConnect is the handle returned by _WinHttpConnect
+type winHttpConnect struct {
+	options winHttpOptions
+	session *winHttpSession
+	scheme  string
+	host    string
+	port    int
+}
+
+//winHttpRequest is the handle returned by _WinHttpOpenRequest
+type winHttpRequest struct {
+	options  winHttpOptions
+	connect  *winHttpConnect
+	method   string
+	path     string
+	secure   bool
+	header   http.Header
+	body     []byte
+	request  *http.Request
+	response *http.Response
+	reader   *bufio.Reader
+	cancel   context.CancelFunc
+}
+
+func init() {
+	//WinHttp
+	stdFunctions["_winhttpopen"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "useragent", DefaultValue: NewToken(tSTRING, defaultUserAgent)},
+			&FunctionArg{Name: "accesstype", DefaultValue: NewToken(tNUMBER, winHttpAccessDefaultProxy)},
+			&FunctionArg{Name: "proxyname", DefaultValue: NewToken(tSTRING, "")},
+			&FunctionArg{Name: "proxybypass", DefaultValue: NewToken(tSTRING, "")},
+			&FunctionArg{Name: "flag", DefaultValue: NewToken(tNUMBER, 0)},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			jar, _ := cookiejar.New(nil)
+			session := &winHttpSession{
+				options: winHttpOptions{
+					userAgent:       args["useragent"].String(),
+					connectTimeout:  60 * time.Second,
+					receiveTimeout:  30 * time.Second,
+					responseTimeout: 90 * time.Second,
+					redirectPolicy:  winHttpRedirectNoDowngrade,
+				},
+				proxy: http.ProxyFromEnvironment,
+				jar:   jar,
+			}
+
+			switch intValue(args["accesstype"]) {
+			case winHttpAccessNoProxy:
+				session.proxy = nil
+			case winHttpAccessNamedProxy:
+				proxy := args["proxyname"].String()
+				if !strings.Contains(proxy, "://") {
+					proxy = "http://" + proxy
+				}
+				proxyURL, err := url.Parse(proxy)
+				if err != nil {
+					vm.SetError(1)
+					return NewToken(tNUMBER, 0), nil
+				}
+				bypass := strings.FieldsFunc(args["proxybypass"].String(), func(r rune) bool { return r == ';' || r == ' ' })
+				session.proxy = func(req *http.Request) (*url.URL, error) {
+					for _, host := range bypass {
+						if wildcardMatch(host, req.URL.Hostname()) || (host == "<local>" && !strings.Contains(req.URL.Hostname(), ".")) {
+							return nil, nil
+						}
+					}
+					return proxyURL, nil
+				}
+			}
+			return vm.AddHandle(session), nil
+		},
+	}
+	stdFunctions["_winhttpconnect"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "session"},
+			&FunctionArg{Name: "servername"},
+			&FunctionArg{Name: "serverport", DefaultValue: NewToken(tNUMBER, 0)},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			session, ok := vm.GetHandle(args["session"].Handle()).(*winHttpSession)
+			if !ok {
+				vm.SetError(1)
+				return NewToken(tNUMBER, 0), nil
+			}
+
+			//Server names may include the scheme, which decides whether requests are secure
+			connect := &winHttpConnect{options: session.options, session: session, host: args["servername"].String(), port: args["serverport"].Int()}
+			if serverURL, err := url.Parse(connect.host); err == nil && serverURL.Host != "" {
+				connect.scheme = strings.ToLower(serverURL.Scheme)
+				connect.host = serverURL.Hostname()
+				if connect.port == 0 && serverURL.Port() != "" {
+					connect.port, _ = strconv.Atoi(serverURL.Port())
+				}
+			}
+			return vm.AddHandle(connect), nil
+		},
+	}
+	stdFunctions["_winhttpopenrequest"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "connect"},
+			&FunctionArg{Name: "verb", DefaultValue: NewToken(tSTRING, "GET")},
+			&FunctionArg{Name: "objectname", DefaultValue: NewToken(tSTRING, "")},
+			&FunctionArg{Name: "version", DefaultValue: NewToken(tSTRING, "HTTP/1.1")},
+			&FunctionArg{Name: "referrer", DefaultValue: NewToken(tSTRING, "")},
+			&FunctionArg{Name: "accepttypes", DefaultValue: NewToken(tSTRING, "")},
+			&FunctionArg{Name: "flags", DefaultValue: NewToken(tNUMBER, 0)},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			connect, ok := vm.GetHandle(args["connect"].Handle()).(*winHttpConnect)
+			if !ok {
+				vm.SetError(1)
+				return NewToken(tNUMBER, 0), nil
+			}
+			request := &winHttpRequest{
+				options: connect.options,
+				connect: connect,
+				method:  strings.ToUpper(args["verb"].String()),
+				path:    args["objectname"].String(),
+				secure:  connect.scheme == "https" || intValue(args["flags"])&winHttpFlagSecure != 0,
+				header:  make(http.Header),
+			}
+			if !strings.HasPrefix(request.path, "/") {
+				request.path = "/" + request.path
+			}
+			if referrer := args["referrer"].String(); referrer != "" {
+				request.header.Set("Referer", referrer)
+			}
+			if accept := args["accepttypes"].String(); accept != "" {
+				request.header.Set("Accept", accept)
+			}
+			return vm.AddHandle(request), nil
+		},
+	}
+	stdFunctions["_winhttpaddrequestheaders"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "request"},
+			&FunctionArg{Name: "header"},
+			&FunctionArg{Name: "modifier", DefaultValue: NewToken(tNUMBER, int64(winHttpAddReqAdd|winHttpAddReqReplace))},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			request, ok := vm.GetHandle(args["request"].Handle()).(*winHttpRequest)
+			if !ok {
+				vm.SetError(1)
+				return NewToken(tBOOLEAN, false), nil
+			}
+			request.addHeaders(args["header"].String(), intValue(args["modifier"]))
+			return NewToken(tBOOLEAN, true), nil
+		},
+	}
+	stdFunctions["_winhttpsendrequest"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "request"},
+			&FunctionArg{Name: "headers", DefaultValue: NewToken(tSTRING, "")},
+			&FunctionArg{Name: "optional", DefaultValue: NewToken(tSTRING, "")},
+			&FunctionArg{Name: "totallength", DefaultValue: NewToken(tNUMBER, 0)},
+			&FunctionArg{Name: "context", DefaultValue: NewToken(tNUMBER, 0)},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			request, ok := vm.GetHandle(args["request"].Handle()).(*winHttpRequest)
+			if !ok {
+				vm.SetError(1)
+				return NewToken(tBOOLEAN, false), nil
+			}
+			request.addHeaders(args["headers"].String(), winHttpAddReqAdd)
+
+			//The request is only sent once the whole body is known, after any _WinHttpWriteData calls
+			request.body = append([]byte{}, args["optional"].Bytes()...)
+			request.closeResponse()
+			return NewToken(tBOOLEAN, true), nil
+		},
+	}
+	stdFunctions["_winhttpwritedata"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "request"},
+			&FunctionArg{Name: "data"},
+			&FunctionArg{Name: "mode", DefaultValue: NewToken(tNUMBER, 0)},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			request, ok := vm.GetHandle(args["request"].Handle()).(*winHttpRequest)
+			if !ok {
+				vm.SetError(1)
+				return NewToken(tBOOLEAN, false), nil
+			}
+			data := args["data"].Bytes()
+			if args["data"].Type != tBINARY && args["mode"].Int() == 0 {
+				data = encodeString(args["data"].String(), encodingANSI)
+			}
+			request.body = append(request.body, data...)
+			vm.SetExtended(len(data))
+			return NewToken(tBOOLEAN, true), nil
+		},
+	}
+	stdFunctions["_winhttpreceiveresponse"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "request"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			request, ok := vm.GetHandle(args["request"].Handle()).(*winHttpRequest)
+			if !ok {
+				vm.SetError(1)
+				return NewToken(tBOOLEAN, false), nil
+			}
+			if err := request.send(vm.context()); err != nil {
+				vm.SetError(1)
+				return NewToken(tBOOLEAN, false), nil
+			}
+			return NewToken(tBOOLEAN, true), nil
+		},
+	}
+	stdFunctions["_winhttpqueryheaders"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "request"},
+			&FunctionArg{Name: "infolevel", DefaultValue: NewToken(tNUMBER, winHttpQueryRawHeadersCRLF)},
+			&FunctionArg{Name: "name", DefaultValue: NewToken(tSTRING, "")},
+			&FunctionArg{Name: "index", DefaultValue: NewToken(tNUMBER, 0)},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			request, ok := vm.GetHandle(args["request"].Handle()).(*winHttpRequest)
+			if !ok {
+				vm.SetError(1)
+				return NewToken(tSTRING, ""), nil
+			}
+			value, ok := request.queryHeader(intValue(args["infolevel"]), args["name"].String(), args["index"].Int())
+			if !ok {
+				vm.SetError(1)
+				return NewToken(tSTRING, ""), nil
+			}
+			if intValue(args["infolevel"])&winHttpQueryFlagNumber != 0 {
+				number, _ := strconv.ParseInt(value, 10, 64)
+				return NewToken(tNUMBER, number), nil
+			}
+			return NewToken(tSTRING, value), nil
+		},
+	}
+	stdFunctions["_winhttpquerydataavailable"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "request"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			request, ok := vm.GetHandle(args["request"].Handle()).(*winHttpRequest)
+			if !ok || request.reader == nil {
+				vm.SetError(1)
+				return NewToken(tBOOLEAN, false), nil
+			}
+
+			//Peeking waits for the next data to arrive without consuming it
+			request.reader.Peek(1)
+			available := request.reader.Buffered()
+			vm.SetExtended(available)
+			return NewToken(tBOOLEAN, available > 0), nil
+		},
+	}
+	stdFunctions["_winhttpreaddata"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "request"},
+			&FunctionArg{Name: "mode", DefaultValue: NewToken(tNUMBER, 0)},
+			&FunctionArg{Name: "numberofbytestoread", DefaultValue: NewToken(tNUMBER, 8192)},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			request, ok := vm.GetHandle(args["request"].Handle()).(*winHttpRequest)
+			if !ok || request.reader == nil || args["numberofbytestoread"].Int() < 1 {
+				vm.SetError(1)
+				return NewToken(tSTRING, ""), nil
+			}
+
+			//Like WinHttpReadData, whatever has arrived is returned without waiting for the requested length
+			data := make([]byte, args["numberofbytestoread"].Int())
+			read, err := request.reader.Read(data)
+			data = data[:read]
+			if err != nil && err != io.EOF {
+				vm.SetError(1)
+				return NewToken(tSTRING, ""), nil
+			}
+			if read == 0 {
+				//The whole response has been read
+				vm.SetError(-1)
+			}
+			vm.SetExtended(read)
+			switch args["mode"].Int() {
+			case 1:
+				return NewToken(tSTRING, decodeString(data, encodingUTF8)), nil
+			case 2:
+				return NewToken(tBINARY, data), nil
+			}
+			return NewToken(tSTRING, decodeString(data, encodingANSI)), nil
+		},
+	}
+	stdFunctions["_winhttpsetoption"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "internet"},
+			&FunctionArg{Name: "option"},
+			&FunctionArg{Name: "setting"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			options := vm.winHttpOptions(args["internet"])
+			if options == nil {
+				vm.SetError(1)
+				return NewToken(tBOOLEAN, false), nil
+			}
+			setting := args["setting"]
+			switch args["option"].Int() {
+			case winHttpOptionConnectTimout:
+				options.connectTimeout = time.Duration(intValue(setting)) * time.Millisecond
+			case winHttpOptionSendTimeout:
+				//Bodies are sent with the headers, so the response timeout covers sending too
+			case winHttpOptionRecvTimeout:
+				options.receiveTimeout = time.Duration(intValue(setting)) * time.Millisecond
+			case winHttpOptionRespTimeout:
+				options.responseTimeout = time.Duration(intValue(setting)) * time.Millisecond
+			case winHttpOptionSecurityFlags:
+				options.securityFlags = int(intValue(setting))
+			case winHttpOptionUserAgent:
+				options.userAgent = setting.String()
+			case winHttpOptionDisable:
+				options.disabled |= int(intValue(setting))
+			case winHttpOptionRedirect:
+				options.redirectPolicy = int(intValue(setting))
+			default:
+				vm.SetError(1)
+				return NewToken(tBOOLEAN, false), nil
+			}
+			return NewToken(tBOOLEAN, true), nil
+		},
+	}
+	stdFunctions["_winhttpsettimeouts"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "internet"},
+			&FunctionArg{Name: "resolvetimeout", DefaultValue: NewToken(tNUMBER, 0)},
+			&FunctionArg{Name: "connecttimeout", DefaultValue: NewToken(tNUMBER, 60000)},
+			&FunctionArg{Name: "sendtimeout", DefaultValue: NewToken(tNUMBER, 30000)},
+			&FunctionArg{Name: "receivetimeout", DefaultValue: NewToken(tNUMBER, 30000)},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			options := vm.winHttpOptions(args["internet"])
+			if options == nil {
+				vm.SetError(1)
+				return NewToken(tBOOLEAN, false), nil
+			}
+			//Resolving is part of connecting, so it shares the connect timeout
+			options.connectTimeout = time.Duration(args["connecttimeout"].Int()) * time.Millisecond
+			options.receiveTimeout = time.Duration(args["receivetimeout"].Int()) * time.Millisecond
+			return NewToken(tBOOLEAN, true), nil
+		},
+	}
+	stdFunctions["_winhttpclosehandle"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "internet"},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			switch handle := vm.GetHandle(args["internet"].Handle()).(type) {
+			case *winHttpRequest:
+				handle.closeResponse()
+			case *winHttpSession:
+				handle.close()
+			case *winHttpConnect:
+			default:
+				vm.SetError(1)
+				return NewToken(tBOOLEAN, false), nil
+			}
+			vm.DestroyHandle(args["internet"].Handle())
+			return NewToken(tBOOLEAN, true), nil
+		},
+	}
+	stdFunctions["_winhttpsimplerequest"] = &Function{
+		Args: []*FunctionArg{
+			&FunctionArg{Name: "connect"},
+			&FunctionArg{Name: "type", DefaultValue: NewToken(tSTRING, "GET")},
+			&FunctionArg{Name: "path", DefaultValue: NewToken(tSTRING, "")},
+			&FunctionArg{Name: "referrer", DefaultValue: NewToken(tSTRING, "")},
+			&FunctionArg{Name: "data", DefaultValue: NewToken(tSTRING, "")},
+			&FunctionArg{Name: "header", DefaultValue: NewToken(tSTRING, "")},
+			&FunctionArg{Name: "getheaders", DefaultValue: NewToken(tBOOLEAN, false)},
+			&FunctionArg{Name: "mode", DefaultValue: NewToken(tNUMBER, 0)},
+		},
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			return vm.winHttpSimpleRequest(args, false), nil
+		},
+	}
+	stdFunctions["_winhttpsimplesslrequest"] = &Function{
+		Args: stdFunctions["_winhttpsimplerequest"].Args,
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			return vm.winHttpSimpleRequest(args, true), nil
+		},
+	}
+}
+
+//winHttpSimpleRequest opens, sends and reads a whole request on a connection as _WinHttpSimpleRequest does,
+//returning the body or an array of the response headers and body
+func (vm *AutoItVM) winHttpSimpleRequest(args map[string]*Token, secure bool) *Token {
+	connect, ok := vm.GetHandle(args["connect"].Handle()).(*winHttpConnect)
+	if !ok {
+		vm.SetError(1)
+		return NewToken(tSTRING, "")
+	}
+	request := &winHttpRequest{
+		options: connect.options,
+		connect: connect,
+		method:  strings.ToUpper(args["type"].String()),
+		path:    "/" + strings.TrimPrefix(args["path"].String(), "/"),
+		secure:  secure || connect.scheme == "https",
+		header:  make(http.Header),
+		body:    args["data"].Bytes(),
+	}
+	if referrer := args["referrer"].String(); referrer != "" {
+		request.header.Set("Referer", referrer)
+	}
+	request.addHeaders(args["header"].String(), winHttpAddReqAdd)
+	if len(request.body) > 0 && request.header.Get("Content-Type") == "" {
+		request.header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	if err := request.send(vm.context()); err != nil {
+		vm.SetError(1)
+		return NewToken(tSTRING, "")
+	}
+	defer request.closeResponse()
+	data, err := io.ReadAll(request.reader)
+	if err != nil {
+		vm.SetError(2)
+		return NewToken(tSTRING, "")
+	}
+
+	body := NewToken(tSTRING, decodeString(data, encodingANSI))
+	switch args["mode"].Int() {
+	case 1:
+		body = NewToken(tSTRING, decodeString(data, encodingUTF8))
+	case 2:
+		body = NewToken(tBINARY, data)
+	}
+	if args["getheaders"].Bool() {
+		headers, _ := request.queryHeader(winHttpQueryRawHeadersCRLF, "", 0)
+		return vm.NewArray([]*Token{NewToken(tSTRING, headers), body})
+	}
+	return body
+}
+
+//winHttpOptions returns the options of a WinHttp handle, or nil if it isn't one
+func (vm *AutoItVM) winHttpOptions(handle *Token) *winHttpOptions {
+	switch internet := vm.GetHandle(handle.Handle()).(type) {
+	case *winHttpSession:
+		return &internet.options
+	case *winHttpConnect:
+		return &internet.options
+	case *winHttpRequest:
+		return &internet.options
+	}
+	return nil
+}
+
+//addHeaders adds the headers given one per line using the _WinHttpAddRequestHeaders modifiers
+func (r *winHttpRequest) addHeaders(headers string, modifier int64) {
+	for _, line := range strings.Split(headers, "\n") {
+		parts := strings.SplitN(strings.TrimSpace(line), ":", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			continue
+		}
+		name, value := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+
+		switch {
+		case modifier&winHttpAddReqIfNew != 0 && r.header.Get(name) != "":
+			continue
+		case modifier&winHttpAddReqReplace != 0:
+			//Replacing with an empty value removes the header
+			r.header.Del(name)
+			if value != "" {
+				r.header.Add(name, value)
+			}
+		default:
+			r.header.Add(name, value)
+		}
+	}
+}
+
+//send sends the request and waits for the response headers, giving up once ctx is done
+func (r *winHttpRequest) send(ctx context.Context) error {
+	r.closeResponse()
+
+	scheme := "http"
+	if r.secure {
+		scheme = "https"
+	}
+	host := r.connect.host
+	if r.connect.port != 0 {
+		host = net.JoinHostPort(host, strconv.Itoa(r.connect.port))
+	}
+
+	var body io.Reader
+	if len(r.body) > 0 {
+		body = bytes.NewReader(r.body)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	req, err := http.NewRequestWithContext(ctx, r.method, scheme+"://"+host+r.path, body)
+	if err != nil {
+		cancel()
+		return err
+	}
+	req.Header = r.header.Clone()
+	if req.Header.Get("User-Agent") == "" && r.options.userAgent != "" {
+		req.Header.Set("User-Agent", r.options.userAgent)
+	}
+
+	//Any of the SECURITY_FLAG_IGNORE_* flags skip certificate checks
+	transport := r.connect.session.transport(winHttpTransportKey{
+		connectTimeout:  r.options.connectTimeout,
+		responseTimeout: r.options.responseTimeout,
+		insecure:        r.options.securityFlags != 0,
+	})
+	client := &http.Client{Transport: transport, CheckRedirect: r.checkRedirect}
+	if r.options.disabled&winHttpDisableCookies == 0 {
+		client.Jar = r.connect.session.jar
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		cancel()
+		return err
+	}
+	r.request = req
+	r.response = resp
+	r.cancel = cancel
+	r.reader = bufio.NewReader(&timeoutReader{body: resp.Body, timeout: r.options.receiveTimeout, cancel: cancel})
+	return nil
+}
+
+//transport returns the transport of the session for the given options, building it on first use
+func (s *winHttpSession) transport(key winHttpTransportKey) *http.Transport {
+	if transport, ok := s.transports[key]; ok {
+		return transport
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = s.proxy
+	transport.DialContext = (&net.Dialer{Timeout: key.connectTimeout}).DialContext
+	transport.ResponseHeaderTimeout = key.responseTimeout
+	if key.insecure {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	if s.transports == nil {
+		s.transports = make(map[winHttpTransportKey]*http.Transport)
+	}
+	s.transports[key] = transport
+	return transport
+}
+
+//close closes the idle connections kept alive by the transports of the session
+func (s *winHttpSession) close() {
+	for _, transport := range s.transports {
+		transport.CloseIdleConnections()
+	}
+	s.transports = nil
+}
+
+//checkRedirect follows redirects according to the redirect policy
+func (r *winHttpRequest) checkRedirect(req *http.Request, via []*http.Request) error {
+	if r.options.disabled&winHttpDisableRedirects != 0 || r.options.redirectPolicy == winHttpRedirectNever {
+		return http.ErrUseLastResponse
+	}
+	if r.options.redirectPolicy == winHttpRedirectNoDowngrade && via[len(via)-1].URL.Scheme == "https" && req.URL.Scheme == "http" {
+		return http.ErrUseLastResponse
+	}
+	if len(via) >= 10 {
+		return fmt.Errorf("winhttp: too many redirects")
+	}
+	return nil
+}
+
+//closeResponse closes the response to the last time the request was sent
+func (r *winHttpRequest) closeResponse() {
+	if r.response != nil {
+		r.response.Body.Close()
+		r.cancel()
+	}
+	r.request = nil
+	r.response = nil
+	r.reader = nil
+}
+
+//queryHeader returns the response or request header for an information level, or the value of a custom header
+func (r *winHttpRequest) queryHeader(infoLevel int64, name string, index int) (string, bool) {
+	if r.response == nil {
+		return "", false
+	}
+	header := r.response.Header
+	if infoLevel&winHttpQueryFlagRequest != 0 {
+		header = r.request.Header
+	}
+
+	switch infoLevel & 0xFFFF {
+	case winHttpQueryVersion:
+		return r.response.Proto, true
+	case winHttpQueryStatusCode:
+		return strconv.Itoa(r.response.StatusCode), true
+	case winHttpQueryStatusText:
+		return strings.TrimSpace(strings.TrimPrefix(r.response.Status, strconv.Itoa(r.response.StatusCode))), true
+	case winHttpQueryRawHeaders, winHttpQueryRawHeadersCRLF:
+		separator := "\r\n"
+		if infoLevel&0xFFFF == winHttpQueryRawHeaders {
+			separator = "\x00"
+		}
+		lines := []string{r.response.Proto + " " + r.response.Status}
+		if infoLevel&winHttpQueryFlagRequest != 0 {
+			lines = []string{r.request.Method + " " + r.request.URL.RequestURI() + " HTTP/1.1"}
+		}
+		names := make([]string, 0)
+		for name := range header {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			for _, value := range header[name] {
+				lines = append(lines, name+": "+value)
+			}
+		}
+		return strings.Join(lines, separator) + separator + separator, true
+	case winHttpQueryCustom:
+	default:
+		known, ok := winHttpQueryHeaders[int(infoLevel&0xFFFF)]
+		if !ok {
+			return "", false
+		}
+		name = known
+	}
+
+	values := header.Values(name)
+	if index < 0 || index >= len(values) {
+		return "", false
+	}
+	return values[index], true
+}
+
+//timeoutReader cancels the request when reading the body takes longer than the receive timeout
+type timeoutReader struct {
+	body    io.Reader
+	timeout time.Duration
+	cancel  context.CancelFunc
+}
+
+//Read reads from the body, giving up after the timeout
+func (t *timeoutReader) Read(data []byte) (int, error) {
+	if t.timeout > 0 {
+		timer := time.AfterFunc(t.timeout, t.cancel)
+		defer timer.Stop()
+	}
+	return t.body.Read(data)
+}
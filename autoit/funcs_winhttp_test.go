@@ -0,0 +1,145 @@
+package autoit
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+//winHttpTestHandler returns the handler of the server for the WinHttp tests
+func winHttpTestHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/headers", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Reply", "reply")
+		fmt.Fprintf(w, "%s|%s", r.Header.Get("X-Test"), r.Header.Get("User-Agent"))
+	})
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, "missing")
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/final", http.StatusFound)
+	})
+	mux.HandleFunc("/final", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "final")
+	})
+	mux.HandleFunc("/setcookie", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc"})
+	})
+	mux.HandleFunc("/cookie", func(w http.ResponseWriter, r *http.Request) {
+		if cookie, err := r.Cookie("session"); err == nil {
+			fmt.Fprint(w, cookie.Value)
+		}
+	})
+	return mux
+}
+
+func TestWinHttp(t *testing.T) {
+	server := httptest.NewServer(winHttpTestHandler())
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		script string
+		want   string
+	}{
+		{"headers", `$r = _WinHttpOpenRequest($c, "GET", "/headers")
+_WinHttpAddRequestHeaders($r, "X-Test: sent")
+_WinHttpSendRequest($r)
+_WinHttpReceiveResponse($r)
+ConsoleWrite(_WinHttpQueryHeaders($r, 65535, "X-Reply") & " " & _WinHttpReadData($r))`, "reply sent|autogo-test"},
+		{"simple request headers", `ConsoleWrite(_WinHttpSimpleRequest($c, "GET", "/headers", "", "", "X-Test: simple"))`, "simple|autogo-test"},
+		{"status code", `$r = _WinHttpOpenRequest($c, "GET", "/status")
+_WinHttpSendRequest($r)
+_WinHttpReceiveResponse($r)
+ConsoleWrite(_WinHttpQueryHeaders($r, 19) & " " & _WinHttpQueryHeaders($r, 20) & " " & _WinHttpReadData($r))`, "404 Not Found missing"},
+		{"redirect followed", `ConsoleWrite(_WinHttpSimpleRequest($c, "GET", "/redirect"))`, "final"},
+		{"redirect disabled", `_WinHttpSetOption($s, 88, 0)
+$r = _WinHttpOpenRequest(_WinHttpConnect($s, $url), "GET", "/redirect")
+_WinHttpSendRequest($r)
+_WinHttpReceiveResponse($r)
+ConsoleWrite(_WinHttpQueryHeaders($r, 19) & " " & _WinHttpQueryHeaders($r, 65535, "Location"))`, "302 /final"},
+		{"cookies kept", `_WinHttpSimpleRequest($c, "GET", "/setcookie")
+ConsoleWrite(_WinHttpSimpleRequest($c, "GET", "/cookie"))`, "abc"},
+		{"cookies disabled", `_WinHttpSetOption($s, 63, 1)
+$c = _WinHttpConnect($s, $url)
+_WinHttpSimpleRequest($c, "GET", "/setcookie")
+ConsoleWrite("[" & _WinHttpSimpleRequest($c, "GET", "/cookie") & "]")`, "[]"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			script := "$url = " + quote(server.URL) + "\n$s = _WinHttpOpen(\"autogo-test\")\n$c = _WinHttpConnect($s, $url)\n" + test.script
+			out, err := runScript(t, script, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out != test.want {
+				t.Errorf("output = %q, want %q", out, test.want)
+			}
+		})
+	}
+}
+
+func TestWinHttpSessionReusesConnections(t *testing.T) {
+	var connections int32
+	server := httptest.NewUnstartedServer(winHttpTestHandler())
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&connections, 1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	script := "$s = _WinHttpOpen()\n$c = _WinHttpConnect($s, " + quote(server.URL) + ")\n"
+	for i := 0; i < 5; i++ {
+		script += "_WinHttpSimpleRequest($c, \"GET\", \"/final\")\n"
+	}
+	script += "_WinHttpCloseHandle($s)"
+	if _, err := runScript(t, script, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if made := atomic.LoadInt32(&connections); made != 1 {
+		t.Errorf("%d connections made, want 1", made)
+	}
+}
+
+func TestWinHttpReadDataStreams(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "first")
+		w.(http.Flusher).Flush()
+		<-release
+		fmt.Fprint(w, "second")
+	}))
+	defer server.Close()
+	defer close(release)
+
+	script := `$s = _WinHttpOpen()
+$r = _WinHttpOpenRequest(_WinHttpConnect($s, ` + quote(server.URL) + `))
+_WinHttpSendRequest($r)
+_WinHttpReceiveResponse($r)
+ConsoleWrite(_WinHttpReadData($r, 0, 100) & " " & @extended)`
+	done := make(chan struct{})
+	var out string
+	var err error
+	go func() {
+		out, err = runScript(t, script, nil)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("_WinHttpReadData waited for the rest of the response")
+	}
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "first 5" {
+		t.Errorf("output = %q, want %q", out, "first 5")
+	}
+}
@@ -1,13 +1,16 @@
 package autoit
 
 import (
+	"fmt"
 	"strings"
+	"sync"
 
 	//"github.com/sqweek/dialog"
 )
 
 var (
-	stdFunctions = map[string]*Function{}
+	stdFunctions      = map[string]*Function{}
+	stdFunctionsMutex sync.RWMutex //Guards stdFunctions against RegisterFunction once VMs are running
 )
 
 //Function holds an AutoIt function
@@ -21,6 +24,91 @@ type Function struct {
 type FunctionArg struct {
 	Name string         //Accessed by Function.Block as $Name
 	DefaultValue *Token //Leave nil to require a value to be set by the caller 
+	Type ArgType        //Type the value is converted to before calling Function.Func, any type if left unset
+	Variadic bool       //Collects this and every following value into an array, only valid for the last argument of a Function.Func
+}
+
+//ArgType describes the type of value a Go func binding expects for an argument
+type ArgType int
+
+const (
+	ArgAny ArgType = iota //Passed as given
+	ArgString             //Converted to a string
+	ArgNumber             //Converted to a number
+	ArgInt                //Converted to an integer number
+	ArgBool               //Converted to a boolean
+	ArgBinary             //Converted to binary data
+	ArgArray              //Must be an array
+	ArgMap                //Must be a map
+	ArgObject             //Must be an object
+)
+
+//RegisterFunction makes a Go func binding available to the scripts of every VM as a built-in function, replacing any
+//built-in function of the same name. Functions report failures to scripts with AutoItVM.SetError and SetExtended.
+//It's safe to call while other VMs are running.
+func RegisterFunction(name string, function *Function) {
+	stdFunctionsMutex.Lock()
+	defer stdFunctionsMutex.Unlock()
+	stdFunctions[strings.ToLower(name)] = function
+}
+
+//RegisterFunction makes a Go func binding available as a built-in function to the scripts of this VM only, taking
+//precedence over the built-in functions of every VM
+func (vm *AutoItVM) RegisterFunction(name string, function *Function) {
+	vm.builtins[strings.ToLower(name)] = function
+}
+
+//builtin returns the built-in function of the given name, if there is one
+func (vm *AutoItVM) builtin(name string) (*Function, bool) {
+	name = strings.ToLower(name)
+	for scope := vm; scope != nil; scope = scope.parentScope {
+		if function, exists := scope.builtins[name]; exists {
+			return function, true
+		}
+	}
+	stdFunctionsMutex.RLock()
+	defer stdFunctionsMutex.RUnlock()
+	function, exists := stdFunctions[name]
+	return function, exists
+}
+
+//convertArg converts the value of an argument to the type the function expects
+func (vm *AutoItVM) convertArg(arg *FunctionArg, tValue *Token) (*Token, error) {
+	switch arg.Type {
+	case ArgString:
+		return NewToken(tSTRING, tValue.String()), nil
+	case ArgNumber:
+		if tValue.Type == tNUMBER || tValue.Type == tDOUBLE {
+			return tValue, nil
+		}
+		number, integer, isInt := parseNumber(tValue.String())
+		if tValue.Type != tSTRING {
+			integer, isInt = intValue(tValue), true
+		}
+		if isInt {
+			return NewToken(tNUMBER, integer), nil
+		}
+		return NewToken(tDOUBLE, number), nil
+	case ArgInt:
+		return NewToken(tNUMBER, intValue(tValue)), nil
+	case ArgBool:
+		return NewToken(tBOOLEAN, tValue.Bool()), nil
+	case ArgBinary:
+		return NewToken(tBINARY, tValue.Bytes()), nil
+	case ArgArray:
+		if vm.GetArray(tValue) == nil {
+			return nil, fmt.Errorf("must be an array")
+		}
+	case ArgMap:
+		if _, ok := vm.GetHandle(tValue.Handle()).(map[string]*Token); !ok {
+			return nil, fmt.Errorf("must be a map")
+		}
+	case ArgObject:
+		if vm.getObject(tValue) == nil {
+			return nil, fmt.Errorf("must be an object")
+		}
+	}
+	return tValue, nil
 }
 
 //FunctionCall holds an AutoIt function call
@@ -30,7 +118,7 @@ type FunctionCall struct {
 }
 
 func (vm *AutoItVM) GetFunction(fc *FunctionCall) *Function {
-	function, exists := stdFunctions[strings.ToLower(fc.Name)]
+	function, exists := vm.builtin(fc.Name)
 	if !exists {
 		function, exists = vm.funcs[strings.ToLower(fc.Name)]
 		if !exists {
@@ -54,13 +142,33 @@ func (vm *AutoItVM) HandleCall(fc *FunctionCall) (*Token, error) {
 	if fc.Block == nil {
 		fc.Block = make([][]*Token, 0)
 	}
-	if len(fc.Block) > len(function.Args) {
+	variadic := function.Func != nil && len(function.Args) > 0 && function.Args[len(function.Args)-1].Variadic
+	if len(fc.Block) > len(function.Args) && !variadic {
 		return nil, vm.Error("%s(%d) called with too many args (%d)", fc.Name, len(function.Args), len(fc.Block))
 	}
 
 	funcArgs := make(map[string]*Token)
 	minimumArgs := len(function.Args)
 	for i := 0; i < len(function.Args); i++ {
+		if variadic && i == len(function.Args)-1 {
+			values := make([]*Token, 0)
+			for j := i; j < len(fc.Block); j++ {
+				tValue, _, err := NewEvaluator(vm, fc.Block[j]).Eval(true)
+				if err != nil {
+					return nil, err
+				}
+				tValue, err = vm.convertArg(function.Args[i], tValue)
+				if err != nil {
+					return nil, vm.Error("%s: argument %d %v", fc.Name, j+1, err)
+				}
+				values = append(values, tValue)
+			}
+			funcArgs[function.Args[i].Name] = vm.NewArray(values)
+			if minimumArgs == len(function.Args) {
+				minimumArgs = i
+			}
+			break
+		}
 		if i < len(fc.Block) {
 			vm.Log("funcArgs %d: evaluating...", i)
 			tValue, _, err := NewEvaluator(vm, fc.Block[i]).Eval(true)
@@ -74,11 +182,17 @@ func (vm *AutoItVM) HandleCall(fc *FunctionCall) (*Token, error) {
 					return nil, vm.Error("%s has no default value for argument %d/%d", fc.Name, i+1, len(function.Args))
 				}
 			}
+			if function.Func != nil && tValue.Type != tDEFAULT {
+				tValue, err = vm.convertArg(function.Args[i], tValue)
+				if err != nil {
+					return nil, vm.Error("%s: argument %d %v", fc.Name, i+1, err)
+				}
+			}
 			funcArgs[function.Args[i].Name] = tValue
 			vm.Log("funcArgs %d: %s = %v", i, function.Args[i].Name, tValue)
 		} else {
 			if function.Args[i].DefaultValue != nil && minimumArgs == len(function.Args) {
-				minimumArgs = i-1
+				minimumArgs = i
 			}
 			funcArgs[function.Args[i].Name] = function.Args[i].DefaultValue
 		}
@@ -91,9 +205,29 @@ func (vm *AutoItVM) HandleCall(fc *FunctionCall) (*Token, error) {
 		vm.SetError(0)
 		vm.SetExtended(0)
 		vm.SetReturnValue(NewToken(tNUMBER, 0))
-		return function.Func(vm, funcArgs)
+		if err := vm.Policy.check(fc.Name, funcArgs); err != nil {
+			if vm.Policy.Fatal {
+				return nil, vm.Error("%w", err)
+			}
+			vm.SetError(1)
+			return NewToken(tNUMBER, 0), nil
+		}
+		tValue, err := function.Func(vm, funcArgs)
+		if err != nil {
+			return nil, err
+		}
+		if err := vm.checkValue(tValue); err != nil {
+			return nil, err
+		}
+		return tValue, vm.checkHandles()
 	}
 	if function.Block != nil {
+		leave, err := vm.enterCall()
+		if err != nil {
+			return nil, err
+		}
+		defer leave()
+
 		vmFunc, _ := vm.ExtendVM(function.Block, false)
 		vmFunc.numParams = len(fc.Block)
 
@@ -108,8 +242,12 @@ func (vm *AutoItVM) HandleCall(fc *FunctionCall) (*Token, error) {
 			}
 		}
 
-		err := vmFunc.Run()
+		err = vmFunc.Run()
 		if err != nil {
+			if stopsScript(err) {
+				//Every level of recursion would wrap these again
+				return nil, err
+			}
 			return nil, vm.Error("error running function block: %v", err)
 		}
 
@@ -0,0 +1,50 @@
+package autoit
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestRegisterFunctionWhileRunning(t *testing.T) {
+	function := &Function{
+		Func: func(vm *AutoItVM, args map[string]*Token) (*Token, error) {
+			return NewString("registered"), nil
+		},
+	}
+	RegisterFunction("_TestRegistered", function)
+
+	//Functions keep being registered until the script is done
+	started, done := make(chan struct{}), make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+				RegisterFunction(fmt.Sprintf("_TestRegistered%d", i%10), function)
+			}
+			if i == 0 {
+				close(started)
+			}
+		}
+	}()
+	<-started
+	script := ""
+	for i := 0; i < 50; i++ {
+		script += "$x = _TestRegistered()\n"
+	}
+	script += "ConsoleWrite($x)"
+	out, err := runScript(t, script, nil)
+	close(done)
+	wg.Wait()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "registered" {
+		t.Errorf("output = %q, want %q", out, "registered")
+	}
+}
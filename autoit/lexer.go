@@ -73,6 +73,13 @@ func (l *Lexer) ReadToken() (*Token, error) {
 					21
 			*/
 			token.Type = tEXTEND
+
+			//Names such as _WinHttpOpen start with an underscore too
+			if l.position < len(l.data) && isIdent(rune(l.data[l.position])) {
+				l.Move(-1)
+				token.Type = tCALL
+				token.Data = l.ReadIdent()
+			}
 		case '"':
 			token.Type = tSTRING
 			token.Data = l.ReadUntil('"', true)
@@ -107,6 +114,12 @@ func (l *Lexer) ReadToken() (*Token, error) {
 		case '$':
 			token.Type = tVARIABLE
 			token.Data = l.ReadIdent()
+		case '.':
+			//Object members such as $oDict.Count, which are never keywords
+			if l.position < len(l.data) && (unicode.IsLetter(rune(l.data[l.position])) || l.data[l.position] == '_') {
+				token.Type = tPROPERTY
+				token.Data = l.ReadIdent()
+			}
 		case '(':
 			token.Type = tLEFTPAREN
 		case ')':
@@ -381,7 +394,8 @@ func (l *Lexer) ReadFlag() string {
 	return read
 }
 func (l *Lexer) ReadUntil(r rune, escapable bool) string {
-	read := ""
+	//Runes are read byte by byte, so collect bytes to keep UTF-8 sequences intact
+	read := make([]byte, 0)
 	for {
 		rTmp, err := l.ReadRune()
 		if err != nil {
@@ -391,7 +405,7 @@ func (l *Lexer) ReadUntil(r rune, escapable bool) string {
 		if escapable && rTmp == '\\' {
 			rTmpEscaped, _ := l.ReadRune()
 			if rTmpEscaped == '\\' {
-				read += "\\"
+				read = append(read, '\\')
 			} else {
 				l.Move(-1)
 			}
@@ -400,9 +414,9 @@ func (l *Lexer) ReadUntil(r rune, escapable bool) string {
 		if rTmp == r {
 			break
 		}
-		read += string(rTmp)
+		read = append(read, byte(rTmp))
 	}
-	return read
+	return string(read)
 }
 func (l *Lexer) Move(pos int) {
 	l.position += pos
@@ -0,0 +1,115 @@
+package autoit
+
+import (
+	"errors"
+	"net"
+)
+
+//defaultMaxRecursion is how deep the functions defined by a script can call each other, which is where AutoIt gives up too
+const defaultMaxRecursion = 5100
+
+//ErrLimitExceeded is matched by the runtime errors of scripts that exceed one of their Limits
+var ErrLimitExceeded = errors.New("resource limit exceeded")
+
+//Limits restricts the resources a script can use, where a zero value leaves a resource unlimited except for MaxRecursion
+type Limits struct {
+	MaxStatements int64 //Statements executed in total
+	MaxRecursion  int   //Nested calls of functions defined by the script, 5100 if left at 0 or unlimited if negative
+	MaxArraySize  int   //Elements in a single array
+	MaxStringSize int   //Characters in a single string
+	MaxHandles    int   //Resources open at once that scripts close themselves: files, searches, sockets, libraries, downloads and WinHttp handles
+}
+
+//vmUsage tracks the resources used by a script across all of its scopes
+type vmUsage struct {
+	statements int64
+	depth      int
+	handles    int
+}
+
+//stopsScript returns true for errors that have to stop the script instead of being handled where they happen
+func stopsScript(err error) bool {
+	return errors.Is(err, ErrLimitExceeded) || errors.Is(err, ErrCancelled) || errors.Is(err, ErrNotAllowed) || errors.Is(err, errScriptExit)
+}
+
+//countsAsHandle returns true for the handles of resources scripts close themselves. Arrays, maps, objects, structs and
+//timers can't be released by a script, so counting them would only limit how often a script creates them.
+func countsAsHandle(value interface{}) bool {
+	switch value.(type) {
+	case *fileHandle, *fileSearch, *dllLibrary, *inetDownload, net.Listener, net.Conn, *winHttpSession, *winHttpConnect, *winHttpRequest:
+		return true
+	}
+	return false
+}
+
+//maxRecursion returns the recursion limit, or 0 if there isn't one
+func (l Limits) maxRecursion() int {
+	if l.MaxRecursion == 0 {
+		return defaultMaxRecursion
+	}
+	if l.MaxRecursion < 0 {
+		return 0
+	}
+	return l.MaxRecursion
+}
+
+//countStatement counts a statement about to be executed against the statement limit
+func (vm *AutoItVM) countStatement() error {
+	vm.usage.statements++
+	if vm.Limits.MaxStatements > 0 && vm.usage.statements > vm.Limits.MaxStatements {
+		return vm.Error("%w: more than %d statements executed", ErrLimitExceeded, vm.Limits.MaxStatements)
+	}
+	return nil
+}
+
+//enterCall counts a call of a function defined by the script against the recursion limit, returning a func to call once it returns
+func (vm *AutoItVM) enterCall() (func(), error) {
+	vm.usage.depth++
+	leave := func() {
+		vm.usage.depth--
+	}
+	if max := vm.Limits.maxRecursion(); max > 0 && vm.usage.depth > max {
+		leave()
+		return nil, vm.Error("%w: recursion level has been exceeded (%d), stopping to prevent a stack overflow", ErrLimitExceeded, max)
+	}
+	return leave, nil
+}
+
+//checkArraySize returns an error if an array of the given size is negative or exceeds the array size limit
+func (vm *AutoItVM) checkArraySize(size int64) error {
+	if size < 0 {
+		return vm.Error("array dimension %d is negative", size)
+	}
+	if vm.Limits.MaxArraySize > 0 && size > int64(vm.Limits.MaxArraySize) {
+		return vm.Error("%w: array of %d elements is larger than %d elements", ErrLimitExceeded, size, vm.Limits.MaxArraySize)
+	}
+	return nil
+}
+
+//checkValue returns an error if a value exceeds the string or array size limits
+func (vm *AutoItVM) checkValue(t *Token) error {
+	if t == nil {
+		return nil
+	}
+	switch t.Type {
+	case tSTRING:
+		if vm.Limits.MaxStringSize > 0 && len(t.Data) > vm.Limits.MaxStringSize {
+			if size := len([]rune(t.Data)); size > vm.Limits.MaxStringSize {
+				return vm.Error("%w: string of %d characters is longer than %d characters", ErrLimitExceeded, size, vm.Limits.MaxStringSize)
+			}
+		}
+	case tHANDLE:
+		if values, ok := vm.GetHandle(t.Handle()).([]*Token); ok {
+			return vm.checkArraySize(int64(len(values)))
+		}
+	}
+	return nil
+}
+
+//checkHandles returns an error if the script holds more handles than the handle limit
+func (vm *AutoItVM) checkHandles() error {
+	if vm.Limits.MaxHandles > 0 && vm.usage.handles > vm.Limits.MaxHandles {
+		return vm.Error("%w: more than %d resources open", ErrLimitExceeded, vm.Limits.MaxHandles)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package autoit
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+//runLimited runs a script with the given limits
+func runLimited(t *testing.T, script string, limits Limits) error {
+	t.Helper()
+	vm, err := NewAutoItScriptVM("limits_test.au3", []byte(script+"\n"), nil)
+	if err != nil {
+		t.Fatalf("can't load script: %v", err)
+	}
+	vm.Limits = limits
+	return vm.Run()
+}
+
+func TestLimitsHandles(t *testing.T) {
+	dir := t.TempDir()
+	open := ""
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		open += "$f = FileOpen(" + quote(filepath.Join(dir, name)) + ", 2)\n"
+	}
+
+	tests := []struct {
+		name     string
+		script   string
+		exceeded bool
+	}{
+		{"arrays", "For $i = 1 To 10\n$a = StringToASCIIArray(\"ab\")\nNext", false},
+		{"maps", "For $i = 1 To 10\nLocal $m[]\nNext", false},
+		{"objects", "For $i = 1 To 10\n$o = ObjCreate(\"Scripting.Dictionary\")\nNext", false},
+		{"structs", "For $i = 1 To 10\n$s = DllStructCreate(\"int\")\nNext", false},
+		{"timers", "For $i = 1 To 10\n$t = TimerInit()\nNext", false},
+		{"files", open, true},
+		{"closed files", "For $i = 1 To 10\n$f = FileOpen(" + quote(filepath.Join(dir, "d.txt")) + ", 2)\nFileClose($f)\nNext", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := runLimited(t, test.script, Limits{MaxHandles: 2})
+			if test.exceeded && !errors.Is(err, ErrLimitExceeded) {
+				t.Errorf("error = %v, want ErrLimitExceeded", err)
+			} else if !test.exceeded && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestLimitsArraySize(t *testing.T) {
+	if err := runLimited(t, "Dim $a[-1]", Limits{}); err == nil {
+		t.Error("negative array size didn't fail")
+	}
+	if err := runLimited(t, "Dim $a[11]", Limits{MaxArraySize: 10}); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("error = %v, want ErrLimitExceeded", err)
+	}
+	if err := runLimited(t, "Dim $a[10]", Limits{MaxArraySize: 10}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -1,8 +1,8 @@
 package autoit
 
 import (
-	"net"
 	"os"
+	"os/user"
 	"path/filepath"
 	"runtime"
 	"strings"
@@ -11,6 +11,8 @@ import (
 
 func (vm *AutoItVM) GetMacro(macro string) (*Token, error) {
 	switch strings.ToLower(macro) {
+	case "appdatadir":
+		return NewToken(tSTRING, specialDir("appdata")), nil
 	case "autoitexe":
 		osExecutable, err := os.Executable()
 		return NewToken(tSTRING, osExecutable), err
@@ -29,6 +31,8 @@ func (vm *AutoItVM) GetMacro(macro string) (*Token, error) {
 	case "computername":
 		hostname, err := os.Hostname()
 		return NewToken(tSTRING, hostname), err
+	case "comspec":
+		return NewToken(tSTRING, comSpec()), nil
 	case "cpuarch":
 		switch runtime.GOARCH {
 		case "amd64":
@@ -41,6 +45,8 @@ func (vm *AutoItVM) GetMacro(macro string) (*Token, error) {
 		return NewToken(tSTRING, "\r"), nil
 	case "crlf":
 		return NewToken(tSTRING, "\r\n"), nil
+	case "desktopdir":
+		return NewToken(tSTRING, specialDir("desktop")), nil
 	case "error":
 		return NewToken(tNUMBER, vm.error), nil
 	case "exitcode":
@@ -49,74 +55,18 @@ func (vm *AutoItVM) GetMacro(macro string) (*Token, error) {
 		return NewToken(tCALL, vm.exitMethod), nil
 	case "extended":
 		return NewToken(tNUMBER, vm.extended), nil
+	case "homedrive":
+		return NewToken(tSTRING, specialDir("homedrive")), nil
+	case "homepath":
+		return NewToken(tSTRING, specialDir("homepath")), nil
 	case "hour":
 		return NewToken(tNUMBER, time.Now().Hour()), nil
-	case "ipaddress1":
-		netInterfaces, err := net.Interfaces()
-		if err == nil && len(netInterfaces) > 0 {
-			addresses, err := netInterfaces[0].Addrs()
-			if err == nil && len(addresses) > 0 {
-				var ip net.IP
-				switch v := addresses[0].(type) {
-				case *net.IPNet:
-					ip = v.IP
-				case *net.IPAddr:
-					ip = v.IP
-				}
-				return NewToken(tSTRING, ip.String()), nil
-			}
-		}
-		return NewToken(tSTRING, "0.0.0.0"), nil
-	case "ipaddress2":
-		netInterfaces, err := net.Interfaces()
-		if err == nil && len(netInterfaces) > 1 {
-			addresses, err := netInterfaces[1].Addrs()
-			if err == nil && len(addresses) > 1 {
-				var ip net.IP
-				switch v := addresses[0].(type) {
-				case *net.IPNet:
-					ip = v.IP
-				case *net.IPAddr:
-					ip = v.IP
-				}
-				return NewToken(tSTRING, ip.String()), nil
-			}
-		}
-		return NewToken(tSTRING, "0.0.0.0"), nil
-	case "ipaddress3":
-		netInterfaces, err := net.Interfaces()
-		if err == nil && len(netInterfaces) > 2 {
-			addresses, err := netInterfaces[2].Addrs()
-			if err == nil && len(addresses) > 2 {
-				var ip net.IP
-				switch v := addresses[0].(type) {
-				case *net.IPNet:
-					ip = v.IP
-				case *net.IPAddr:
-					ip = v.IP
-				}
-				return NewToken(tSTRING, ip.String()), nil
-			}
-		}
-		return NewToken(tSTRING, "0.0.0.0"), nil
-	case "ipaddress4":
-		netInterfaces, err := net.Interfaces()
-		if err == nil && len(netInterfaces) > 3 {
-			addresses, err := netInterfaces[3].Addrs()
-			if err == nil && len(addresses) > 3 {
-				var ip net.IP
-				switch v := addresses[0].(type) {
-				case *net.IPNet:
-					ip = v.IP
-				case *net.IPAddr:
-					ip = v.IP
-				}
-				return NewToken(tSTRING, ip.String()), nil
-			}
-		}
-		return NewToken(tSTRING, "0.0.0.0"), nil
+	case "ipaddress1", "ipaddress2", "ipaddress3", "ipaddress4":
+		return NewToken(tSTRING, vm.ipAddress(int(macro[len(macro)-1]-'0'))), nil
 	case "lf":
 		return NewToken(tSTRING, "\n"), nil
+	case "localappdatadir":
+		return NewToken(tSTRING, specialDir("localappdata")), nil
 	case "mday":
 		return NewToken(tNUMBER, time.Now().Day()), nil
 	case "min":
@@ -125,6 +75,8 @@ func (vm *AutoItVM) GetMacro(macro string) (*Token, error) {
 		return NewToken(tNUMBER, time.Now().Month()), nil
 	case "msec":
 		return NewToken(tNUMBER, float64(time.Now().UnixNano()) / 1000000), nil
+	case "mydocumentsdir":
+		return NewToken(tSTRING, specialDir("documents")), nil
 	case "numparams":
 		return NewToken(tNUMBER, vm.numParams), nil
 	case "osarch":
@@ -137,12 +89,20 @@ func (vm *AutoItVM) GetMacro(macro string) (*Token, error) {
 			return NewToken(tSTRING, "IA64"), nil
 		}
 		return NewToken(tSTRING, runtime.GOARCH), nil
+	case "osbuild":
+		return NewToken(tSTRING, osBuild()), nil
+	case "oslang":
+		return NewToken(tSTRING, osLang()), nil
 	case "ostype":
 		switch runtime.GOOS {
 		case "windows":
 			return NewToken(tSTRING, "WIN32_NT"), nil
 		}
 		return NewToken(tSTRING, runtime.GOOS), nil
+	case "osversion":
+		return NewToken(tSTRING, osVersion()), nil
+	case "programfilesdir":
+		return NewToken(tSTRING, specialDir("programfiles")), nil
 	case "scriptdir":
 		return NewToken(tSTRING, filepath.Dir(vm.scriptPath)), nil
 	case "scriptfullpath":
@@ -151,12 +111,61 @@ func (vm *AutoItVM) GetMacro(macro string) (*Token, error) {
 		return NewToken(tSTRING, filepath.Base(vm.scriptPath)), nil
 	case "sec":
 		return NewToken(tNUMBER, time.Now().Second()), nil
+	case "startupdir":
+		return NewToken(tSTRING, specialDir("startup")), nil
+	case "sw_disable":
+		return NewToken(tNUMBER, 65), nil
+	case "sw_enable":
+		return NewToken(tNUMBER, 64), nil
+	case "sw_hide":
+		return NewToken(tNUMBER, 0), nil
+	case "sw_lock":
+		return NewToken(tNUMBER, 66), nil
+	case "sw_maximize", "sw_showmaximized":
+		return NewToken(tNUMBER, 3), nil
+	case "sw_minimize":
+		return NewToken(tNUMBER, 6), nil
+	case "sw_restore":
+		return NewToken(tNUMBER, 9), nil
+	case "sw_show":
+		return NewToken(tNUMBER, 5), nil
+	case "sw_showdefault":
+		return NewToken(tNUMBER, 10), nil
+	case "sw_showminimized":
+		return NewToken(tNUMBER, 2), nil
+	case "sw_showminnoactive":
+		return NewToken(tNUMBER, 7), nil
+	case "sw_showna":
+		return NewToken(tNUMBER, 8), nil
+	case "sw_shownoactivate":
+		return NewToken(tNUMBER, 4), nil
+	case "sw_shownormal":
+		return NewToken(tNUMBER, 1), nil
+	case "sw_unlock":
+		return NewToken(tNUMBER, 67), nil
+	case "systemdir":
+		return NewToken(tSTRING, specialDir("system")), nil
 	case "tab":
 		return NewToken(tSTRING, "\t"), nil
 	case "tempdir":
 		return NewToken(tSTRING, os.TempDir()), nil
+	case "username":
+		current, err := user.Current()
+		if err != nil {
+			return NewToken(tSTRING, os.Getenv("USER")), nil
+		}
+		//Windows includes the domain in the name
+		username := current.Username
+		if slash := strings.LastIndex(username, "\\"); slash >= 0 {
+			username = username[slash+1:]
+		}
+		return NewToken(tSTRING, username), nil
+	case "userprofiledir":
+		return NewToken(tSTRING, specialDir("home")), nil
 	case "wday":
 		return NewToken(tNUMBER, int(time.Now().Weekday()) + 1), nil
+	case "windowsdir":
+		return NewToken(tSTRING, specialDir("windows")), nil
 	case "workingdir":
 		wd, err := os.Getwd()
 		if err != nil {
@@ -0,0 +1,171 @@
+package autoit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//Comparison modes of Scripting.Dictionary
+const (
+	compareBinary = 0
+	compareText   = 1
+)
+
+//dictionary emulates Scripting.Dictionary, keeping its keys in the order they were added
+type dictionary struct {
+	keys        []*Token
+	items       []*Token
+	index       map[string]int
+	compareMode int
+}
+
+func init() {
+	RegisterObjectClass("Scripting.Dictionary", func(vm *AutoItVM) (Object, error) {
+		return &dictionary{index: make(map[string]int)}, nil
+	})
+}
+
+//ObjectName returns the name reported by ObjName
+func (d *dictionary) ObjectName() string {
+	return "Dictionary"
+}
+
+//Get returns the value of a property
+func (d *dictionary) Get(vm *AutoItVM, name string) (*Token, error) {
+	switch strings.ToLower(name) {
+	case "count":
+		return NewToken(tNUMBER, len(d.keys)), nil
+	case "comparemode":
+		return NewToken(tNUMBER, d.compareMode), nil
+	}
+	return d.Call(vm, name, nil)
+}
+
+//Set sets the value of a property
+func (d *dictionary) Set(vm *AutoItVM, name string, args []*Token, value *Token) error {
+	switch strings.ToLower(name) {
+	case "item":
+		if len(args) != 1 {
+			return errMemberArgs(d, name)
+		}
+		if i, exists := d.find(args[0]); exists {
+			d.items[i] = value
+			return nil
+		}
+		d.add(args[0], value)
+		return nil
+	case "key":
+		if len(args) != 1 {
+			return errMemberArgs(d, name)
+		}
+		i, exists := d.find(args[0])
+		if !exists {
+			return fmt.Errorf("element %s not found", args[0].String())
+		}
+		if _, exists := d.find(value); exists {
+			return fmt.Errorf("key %s is already associated with an element of this collection", value.String())
+		}
+		delete(d.index, d.normalize(args[0]))
+		d.keys[i] = value
+		d.index[d.normalize(value)] = i
+		return nil
+	case "comparemode":
+		if len(d.keys) > 0 {
+			return fmt.Errorf("comparison mode can't be changed once the dictionary holds items")
+		}
+		d.compareMode = int(intValue(value))
+		return nil
+	}
+	return errUnknownMember(d, name)
+}
+
+//Call calls a method
+func (d *dictionary) Call(vm *AutoItVM, name string, args []*Token) (*Token, error) {
+	switch strings.ToLower(name) {
+	case "add":
+		if len(args) != 2 {
+			return nil, errMemberArgs(d, name)
+		}
+		if _, exists := d.find(args[0]); exists {
+			return nil, fmt.Errorf("key %s is already associated with an element of this collection", args[0].String())
+		}
+		d.add(args[0], args[1])
+		return nil, nil
+	case "exists":
+		if len(args) != 1 {
+			return nil, errMemberArgs(d, name)
+		}
+		_, exists := d.find(args[0])
+		return NewToken(tBOOLEAN, exists), nil
+	case "item":
+		if len(args) != 1 {
+			return nil, errMemberArgs(d, name)
+		}
+		//Reading a missing item adds it, as it does in VBScript
+		i, exists := d.find(args[0])
+		if !exists {
+			d.add(args[0], NewToken(tSTRING, ""))
+			return NewToken(tSTRING, ""), nil
+		}
+		return d.items[i], nil
+	case "items":
+		return vm.NewArray(append([]*Token{}, d.items...)), nil
+	case "keys":
+		return vm.NewArray(append([]*Token{}, d.keys...)), nil
+	case "remove":
+		if len(args) != 1 {
+			return nil, errMemberArgs(d, name)
+		}
+		i, exists := d.find(args[0])
+		if !exists {
+			return nil, fmt.Errorf("element %s not found", args[0].String())
+		}
+		d.keys = append(d.keys[:i], d.keys[i+1:]...)
+		d.items = append(d.items[:i], d.items[i+1:]...)
+		d.reindex()
+		return nil, nil
+	case "removeall":
+		d.keys, d.items = nil, nil
+		d.reindex()
+		return nil, nil
+	case "count", "comparemode":
+		return d.Get(vm, name)
+	}
+	return nil, errUnknownMember(d, name)
+}
+
+//normalize returns the lookup key for a key, where numbers and strings are never equal
+func (d *dictionary) normalize(key *Token) string {
+	switch key.Type {
+	case tNUMBER, tDOUBLE:
+		return "n:" + strconv.FormatFloat(key.Float64(), 'g', -1, 64)
+	case tSTRING:
+		if d.compareMode == compareText {
+			return "s:" + strings.ToLower(key.Data)
+		}
+		return "s:" + key.Data
+	}
+	return string(key.Type) + ":" + key.Data
+}
+
+//find returns the position of a key
+func (d *dictionary) find(key *Token) (int, bool) {
+	i, exists := d.index[d.normalize(key)]
+	return i, exists
+}
+
+//add appends a new key and item
+func (d *dictionary) add(key, item *Token) {
+	d.index[d.normalize(key)] = len(d.keys)
+	d.keys = append(d.keys, key)
+	d.items = append(d.items, item)
+}
+
+//reindex rebuilds the positions of every key
+func (d *dictionary) reindex() {
+	d.index = make(map[string]int)
+	for i, key := range d.keys {
+		d.index[d.normalize(key)] = i
+	}
+}
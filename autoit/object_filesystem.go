@@ -0,0 +1,659 @@
+package autoit
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+//I/O modes of FileSystemObject text streams
+const (
+	fsoForReading   = 1
+	fsoForWriting   = 2
+	fsoForAppending = 8
+)
+
+//Text formats of FileSystemObject text streams
+const (
+	fsoFormatDefault = -2
+	fsoFormatUnicode = -1
+	fsoFormatASCII   = 0
+)
+
+//File attributes reported by FileSystemObject files and folders
+const (
+	fsoAttrReadOnly  = 1
+	fsoAttrHidden    = 2
+	fsoAttrDirectory = 16
+	fsoAttrArchive   = 32
+)
+
+//fileSystemObject emulates Scripting.FileSystemObject
+type fileSystemObject struct{}
+
+//fsoFile is a File object returned by a FileSystemObject
+type fsoFile struct {
+	path string
+}
+
+//fsoFolder is a Folder object returned by a FileSystemObject
+type fsoFolder struct {
+	path string
+}
+
+//fsoCollection holds the Files or SubFolders of a folder, keyed by name
+type fsoCollection struct {
+	names []string
+	items []*Token
+}
+
+//fsoTextStream is a TextStream object reading or writing a text file
+type fsoTextStream struct {
+	file   *fileHandle
+	iomode int
+	line   int
+	closed bool
+}
+
+func init() {
+	RegisterObjectClass("Scripting.FileSystemObject", func(vm *AutoItVM) (Object, error) {
+		return &fileSystemObject{}, nil
+	})
+}
+
+//ObjectName returns the name reported by ObjName
+func (fso *fileSystemObject) ObjectName() string {
+	return "FileSystemObject"
+}
+
+//Get returns the value of a property
+func (fso *fileSystemObject) Get(vm *AutoItVM, name string) (*Token, error) {
+	return fso.Call(vm, name, nil)
+}
+
+//Set sets the value of a property
+func (fso *fileSystemObject) Set(vm *AutoItVM, name string, args []*Token, value *Token) error {
+	return errUnknownMember(fso, name)
+}
+
+//Call calls a method
+func (fso *fileSystemObject) Call(vm *AutoItVM, name string, args []*Token) (*Token, error) {
+	method := strings.ToLower(name)
+	switch method {
+	case "gettempname":
+		return NewToken(tSTRING, fmt.Sprintf("rad%05X.tmp", vm.random.Intn(0x100000))), nil
+	case "getspecialfolder":
+		if len(args) != 1 {
+			return nil, errMemberArgs(fso, name)
+		}
+		switch intValue(args[0]) {
+		case 0:
+			return vm.AddHandle(&fsoFolder{path: specialDir("windows")}), nil
+		case 1:
+			return vm.AddHandle(&fsoFolder{path: specialDir("system")}), nil
+		case 2:
+			return vm.AddHandle(&fsoFolder{path: os.TempDir()}), nil
+		}
+		return nil, fmt.Errorf("invalid special folder %s", args[0].String())
+	}
+
+	//Every other method takes a path first
+	if len(args) == 0 {
+		return nil, errMemberArgs(fso, name)
+	}
+	path := args[0].String()
+
+	switch method {
+	case "buildpath":
+		if len(args) != 2 {
+			return nil, errMemberArgs(fso, name)
+		}
+		if path == "" || strings.HasSuffix(path, string(os.PathSeparator)) {
+			return NewToken(tSTRING, path+args[1].String()), nil
+		}
+		return NewToken(tSTRING, path+string(os.PathSeparator)+args[1].String()), nil
+	case "copyfile", "movefile":
+		if len(args) < 2 {
+			return nil, errMemberArgs(fso, name)
+		}
+		flag, transfer := 0, copyFile
+		if method == "copyfile" && objectArg(args, 2, NewToken(tBOOLEAN, true)).Bool() {
+			flag = 1
+		}
+		if method == "movefile" {
+			transfer = moveFile
+		}
+		if !transferFiles(path, args[1].String(), flag, transfer) {
+			return nil, fmt.Errorf("could not %s %s to %s", method[:4], path, args[1].String())
+		}
+		return nil, nil
+	case "copyfolder":
+		if len(args) < 2 {
+			return nil, errMemberArgs(fso, name)
+		}
+		return nil, copyDir(path, args[1].String(), objectArg(args, 2, NewToken(tBOOLEAN, true)).Bool())
+	case "movefolder":
+		if len(args) != 2 {
+			return nil, errMemberArgs(fso, name)
+		}
+		return nil, os.Rename(path, args[1].String())
+	case "createfolder":
+		if err := os.Mkdir(path, 0777); err != nil {
+			return nil, err
+		}
+		return vm.AddHandle(&fsoFolder{path: path}), nil
+	case "createtextfile":
+		return createTextStream(vm, path, objectArg(args, 1, NewToken(tBOOLEAN, true)).Bool(), objectArg(args, 2, NewToken(tBOOLEAN, false)).Bool())
+	case "opentextfile":
+		iomode := int(intValue(objectArg(args, 1, NewToken(tNUMBER, fsoForReading))))
+		create := objectArg(args, 2, NewToken(tBOOLEAN, false)).Bool()
+		format := int(intValue(objectArg(args, 3, NewToken(tNUMBER, fsoFormatASCII))))
+		if _, err := os.Stat(path); err != nil && (!create || iomode == fsoForReading) {
+			return nil, err
+		}
+		return openTextStream(vm, path, iomode, format)
+	case "deletefile", "deletefolder":
+		matches := matchFiles(path, method == "deletefolder", false)
+		deleted := 0
+		for _, match := range matches {
+			stat, err := os.Stat(match)
+			if err != nil || stat.IsDir() != (method == "deletefolder") {
+				continue
+			}
+			if err := os.RemoveAll(match); err != nil {
+				return nil, err
+			}
+			deleted++
+		}
+		if deleted == 0 {
+			return nil, fmt.Errorf("%s not found", path)
+		}
+		return nil, nil
+	case "fileexists":
+		stat, err := os.Stat(path)
+		return NewToken(tBOOLEAN, err == nil && !stat.IsDir()), nil
+	case "folderexists":
+		stat, err := os.Stat(path)
+		return NewToken(tBOOLEAN, err == nil && stat.IsDir()), nil
+	case "getabsolutepathname":
+		absolute, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		return NewToken(tSTRING, absolute), nil
+	case "getbasename":
+		base := fsoBaseName(path)
+		return NewToken(tSTRING, strings.TrimSuffix(base, filepath.Ext(base))), nil
+	case "getextensionname":
+		return NewToken(tSTRING, strings.TrimPrefix(filepath.Ext(fsoBaseName(path)), ".")), nil
+	case "getfilename":
+		return NewToken(tSTRING, fsoBaseName(path)), nil
+	case "getparentfoldername":
+		return NewToken(tSTRING, fsoParentName(path)), nil
+	case "getfile":
+		stat, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if stat.IsDir() {
+			return nil, fmt.Errorf("%s is a directory", path)
+		}
+		return vm.AddHandle(&fsoFile{path: path}), nil
+	case "getfolder":
+		stat, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !stat.IsDir() {
+			return nil, fmt.Errorf("%s is not a directory", path)
+		}
+		return vm.AddHandle(&fsoFolder{path: path}), nil
+	}
+	return nil, errUnknownMember(fso, name)
+}
+
+//fsoBaseName returns the last element of a path, or an empty string if there isn't one
+func fsoBaseName(path string) string {
+	path = strings.TrimRight(path, string(os.PathSeparator))
+	if path == "" {
+		return ""
+	}
+	return filepath.Base(path)
+}
+
+//fsoParentName returns the path without its last element, or an empty string if there's no parent
+func fsoParentName(path string) string {
+	path = strings.TrimRight(path, string(os.PathSeparator))
+	if !strings.Contains(path, string(os.PathSeparator)) {
+		return ""
+	}
+	return filepath.Dir(path)
+}
+
+//fsoAttributes converts file information into FileSystemObject attributes
+func fsoAttributes(stat os.FileInfo) int {
+	attributes := 0
+	if stat.Mode().Perm()&0200 == 0 {
+		attributes |= fsoAttrReadOnly
+	}
+	if strings.HasPrefix(stat.Name(), ".") {
+		attributes |= fsoAttrHidden
+	}
+	if stat.IsDir() {
+		attributes |= fsoAttrDirectory
+	} else {
+		attributes |= fsoAttrArchive
+	}
+	return attributes
+}
+
+//fsoCommonProperty returns the properties shared by files and folders
+func fsoCommonProperty(vm *AutoItVM, path, name string) (*Token, bool, error) {
+	switch strings.ToLower(name) {
+	case "name":
+		return NewToken(tSTRING, fsoBaseName(path)), true, nil
+	case "path":
+		return NewToken(tSTRING, path), true, nil
+	case "parentfolder":
+		parent := fsoParentName(path)
+		if parent == "" {
+			return NewToken(tSTRING, ""), true, nil
+		}
+		return vm.AddHandle(&fsoFolder{path: parent}), true, nil
+	case "attributes", "datecreated", "datelastmodified", "datelastaccessed":
+	default:
+		return nil, false, nil
+	}
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, true, err
+	}
+	switch strings.ToLower(name) {
+	case "attributes":
+		return NewToken(tNUMBER, fsoAttributes(stat)), true, nil
+	case "datecreated":
+		return NewToken(tSTRING, fileCreationTime(stat).Format("20060102150405")), true, nil
+	case "datelastmodified":
+		return NewToken(tSTRING, stat.ModTime().Format("20060102150405")), true, nil
+	}
+	return NewToken(tSTRING, fileAccessTime(stat).Format("20060102150405")), true, nil
+}
+
+//fsoRename renames a file or folder in place, as done by setting its Name
+func fsoRename(path *string, name string) error {
+	target := filepath.Join(filepath.Dir(*path), name)
+	if err := os.Rename(*path, target); err != nil {
+		return err
+	}
+	*path = target
+	return nil
+}
+
+//ObjectName returns the name reported by ObjName
+func (f *fsoFile) ObjectName() string {
+	return "File"
+}
+
+//Get returns the value of a property
+func (f *fsoFile) Get(vm *AutoItVM, name string) (*Token, error) {
+	if value, ok, err := fsoCommonProperty(vm, f.path, name); ok {
+		return value, err
+	}
+	switch strings.ToLower(name) {
+	case "size":
+		stat, err := os.Stat(f.path)
+		if err != nil {
+			return nil, err
+		}
+		return NewToken(tNUMBER, stat.Size()), nil
+	case "type":
+		if ext := filepath.Ext(f.path); ext != "" {
+			return NewToken(tSTRING, strings.ToUpper(ext[1:])+" File"), nil
+		}
+		return NewToken(tSTRING, "File"), nil
+	}
+	return f.Call(vm, name, nil)
+}
+
+//Set sets the value of a property
+func (f *fsoFile) Set(vm *AutoItVM, name string, args []*Token, value *Token) error {
+	if strings.ToLower(name) == "name" {
+		return fsoRename(&f.path, value.String())
+	}
+	return errUnknownMember(f, name)
+}
+
+//Call calls a method
+func (f *fsoFile) Call(vm *AutoItVM, name string, args []*Token) (*Token, error) {
+	switch strings.ToLower(name) {
+	case "copy":
+		if len(args) == 0 {
+			return nil, errMemberArgs(f, name)
+		}
+		return nil, copyFile(f.path, args[0].String(), objectArg(args, 1, NewToken(tBOOLEAN, true)).Bool())
+	case "move":
+		if len(args) != 1 {
+			return nil, errMemberArgs(f, name)
+		}
+		if err := moveFile(f.path, args[0].String(), false); err != nil {
+			return nil, err
+		}
+		f.path = args[0].String()
+		return nil, nil
+	case "delete":
+		return nil, os.Remove(f.path)
+	case "openastextstream":
+		iomode := int(intValue(objectArg(args, 0, NewToken(tNUMBER, fsoForReading))))
+		format := int(intValue(objectArg(args, 1, NewToken(tNUMBER, fsoFormatASCII))))
+		return openTextStream(vm, f.path, iomode, format)
+	}
+	return nil, errUnknownMember(f, name)
+}
+
+//ObjectName returns the name reported by ObjName
+func (f *fsoFolder) ObjectName() string {
+	return "Folder"
+}
+
+//Get returns the value of a property
+func (f *fsoFolder) Get(vm *AutoItVM, name string) (*Token, error) {
+	if value, ok, err := fsoCommonProperty(vm, f.path, name); ok {
+		return value, err
+	}
+	switch strings.ToLower(name) {
+	case "isrootfolder":
+		return NewToken(tBOOLEAN, filepath.Dir(f.path) == f.path), nil
+	case "size":
+		size := int64(0)
+		err := filepath.Walk(f.path, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() {
+				size += info.Size()
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return NewToken(tNUMBER, size), nil
+	case "type":
+		return NewToken(tSTRING, "File folder"), nil
+	case "files", "subfolders":
+		entries, err := os.ReadDir(f.path)
+		if err != nil {
+			return nil, err
+		}
+		collection := &fsoCollection{}
+		for _, entry := range entries {
+			if entry.IsDir() != (strings.ToLower(name) == "subfolders") {
+				continue
+			}
+			path := filepath.Join(f.path, entry.Name())
+			collection.names = append(collection.names, entry.Name())
+			if entry.IsDir() {
+				collection.items = append(collection.items, vm.AddHandle(&fsoFolder{path: path}))
+			} else {
+				collection.items = append(collection.items, vm.AddHandle(&fsoFile{path: path}))
+			}
+		}
+		return vm.AddHandle(collection), nil
+	}
+	return f.Call(vm, name, nil)
+}
+
+//Set sets the value of a property
+func (f *fsoFolder) Set(vm *AutoItVM, name string, args []*Token, value *Token) error {
+	if strings.ToLower(name) == "name" {
+		return fsoRename(&f.path, value.String())
+	}
+	return errUnknownMember(f, name)
+}
+
+//Call calls a method
+func (f *fsoFolder) Call(vm *AutoItVM, name string, args []*Token) (*Token, error) {
+	switch strings.ToLower(name) {
+	case "copy":
+		if len(args) == 0 {
+			return nil, errMemberArgs(f, name)
+		}
+		return nil, copyDir(f.path, args[0].String(), objectArg(args, 1, NewToken(tBOOLEAN, true)).Bool())
+	case "move":
+		if len(args) != 1 {
+			return nil, errMemberArgs(f, name)
+		}
+		if err := os.Rename(f.path, args[0].String()); err != nil {
+			return nil, err
+		}
+		f.path = args[0].String()
+		return nil, nil
+	case "delete":
+		return nil, os.RemoveAll(f.path)
+	case "createtextfile":
+		if len(args) == 0 {
+			return nil, errMemberArgs(f, name)
+		}
+		path := filepath.Join(f.path, args[0].String())
+		return createTextStream(vm, path, objectArg(args, 1, NewToken(tBOOLEAN, true)).Bool(), objectArg(args, 2, NewToken(tBOOLEAN, false)).Bool())
+	}
+	return nil, errUnknownMember(f, name)
+}
+
+//ObjectName returns the name reported by ObjName
+func (c *fsoCollection) ObjectName() string {
+	return "Collection"
+}
+
+//Get returns the value of a property
+func (c *fsoCollection) Get(vm *AutoItVM, name string) (*Token, error) {
+	if strings.ToLower(name) == "count" {
+		return NewToken(tNUMBER, len(c.items)), nil
+	}
+	return c.Call(vm, name, nil)
+}
+
+//Set sets the value of a property
+func (c *fsoCollection) Set(vm *AutoItVM, name string, args []*Token, value *Token) error {
+	return errUnknownMember(c, name)
+}
+
+//Call calls a method
+func (c *fsoCollection) Call(vm *AutoItVM, name string, args []*Token) (*Token, error) {
+	switch strings.ToLower(name) {
+	case "count":
+		return c.Get(vm, name)
+	case "item":
+		if len(args) != 1 {
+			return nil, errMemberArgs(c, name)
+		}
+		for i, itemName := range c.names {
+			if strings.EqualFold(itemName, args[0].String()) {
+				return c.items[i], nil
+			}
+		}
+		return nil, fmt.Errorf("%s not found in collection", args[0].String())
+	}
+	return nil, errUnknownMember(c, name)
+}
+
+//createTextStream creates a text file and opens it for writing
+func createTextStream(vm *AutoItVM, path string, overwrite, unicode bool) (*Token, error) {
+	if !overwrite {
+		if _, err := os.Stat(path); err == nil {
+			return nil, fmt.Errorf("%s already exists", path)
+		}
+	}
+	format := fsoFormatASCII
+	if unicode {
+		format = fsoFormatUnicode
+	}
+	return openTextStream(vm, path, fsoForWriting, format)
+}
+
+//openTextStream opens a text file for reading, writing or appending in the given format
+func openTextStream(vm *AutoItVM, path string, iomode, format int) (*Token, error) {
+	mode := fileModeRead
+	switch iomode {
+	case fsoForReading:
+	case fsoForWriting:
+		mode = fileModeOverwrite
+	case fsoForAppending:
+		mode = fileModeAppend
+	default:
+		return nil, fmt.Errorf("invalid I/O mode %d", iomode)
+	}
+
+	//The default format reads files the same way FileRead does, detecting Unicode files
+	switch format {
+	case fsoFormatUnicode:
+		mode |= fileModeUTF16LE
+	case fsoFormatASCII:
+		if iomode != fsoForReading {
+			mode |= fileModeANSI
+		}
+	}
+
+	file, err := openFile(path, mode)
+	if err != nil {
+		return nil, err
+	}
+	return vm.AddHandle(&fsoTextStream{file: file, iomode: iomode, line: 1}), nil
+}
+
+//ObjectName returns the name reported by ObjName
+func (s *fsoTextStream) ObjectName() string {
+	return "TextStream"
+}
+
+//Get returns the value of a property
+func (s *fsoTextStream) Get(vm *AutoItVM, name string) (*Token, error) {
+	switch strings.ToLower(name) {
+	case "line":
+		return NewToken(tNUMBER, s.line), nil
+	case "atendofstream", "atendofline":
+		if err := s.check(fsoForReading); err != nil {
+			return nil, err
+		}
+		next := s.peek()
+		if strings.ToLower(name) == "atendofstream" {
+			return NewToken(tBOOLEAN, next == -1), nil
+		}
+		return NewToken(tBOOLEAN, next == -1 || next == '\r' || next == '\n'), nil
+	}
+	return s.Call(vm, name, nil)
+}
+
+//Set sets the value of a property
+func (s *fsoTextStream) Set(vm *AutoItVM, name string, args []*Token, value *Token) error {
+	return errUnknownMember(s, name)
+}
+
+//Call calls a method
+func (s *fsoTextStream) Call(vm *AutoItVM, name string, args []*Token) (*Token, error) {
+	switch strings.ToLower(name) {
+	case "close":
+		if s.closed {
+			return nil, nil
+		}
+		s.closed = true
+		return nil, s.file.file.Close()
+	case "read", "skip":
+		if len(args) != 1 {
+			return nil, errMemberArgs(s, name)
+		}
+		if err := s.check(fsoForReading); err != nil {
+			return nil, err
+		}
+		text, _, err := s.file.read(int(intValue(args[0])))
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		s.line += strings.Count(text.String(), "\n")
+		if strings.ToLower(name) == "skip" {
+			return nil, nil
+		}
+		return text, nil
+	case "readall":
+		if err := s.check(fsoForReading); err != nil {
+			return nil, err
+		}
+		text, _, err := s.file.read(0)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		s.line += strings.Count(text.String(), "\n")
+		return text, nil
+	case "readline", "skipline":
+		if err := s.check(fsoForReading); err != nil {
+			return nil, err
+		}
+		line, err := s.file.readLine()
+		if err == io.EOF {
+			return nil, fmt.Errorf("input past end of file")
+		}
+		if err != nil {
+			return nil, err
+		}
+		s.line++
+		if strings.ToLower(name) == "skipline" {
+			return nil, nil
+		}
+		return NewToken(tSTRING, line), nil
+	case "write", "writeline", "writeblanklines":
+		if err := s.check(fsoForWriting); err != nil {
+			return nil, err
+		}
+		text := ""
+		switch strings.ToLower(name) {
+		case "write":
+			if len(args) != 1 {
+				return nil, errMemberArgs(s, name)
+			}
+			text = args[0].String()
+		case "writeline":
+			text = objectArg(args, 0, NewToken(tSTRING, "")).String() + "\r\n"
+		case "writeblanklines":
+			if len(args) != 1 {
+				return nil, errMemberArgs(s, name)
+			}
+			text = strings.Repeat("\r\n", int(intValue(args[0])))
+		}
+		s.line += strings.Count(text, "\n")
+		return nil, s.file.write(NewToken(tSTRING, text))
+	}
+	return nil, errUnknownMember(s, name)
+}
+
+//check makes sure the stream is open in a mode allowing the requested access
+func (s *fsoTextStream) check(iomode int) error {
+	if s.closed {
+		return fmt.Errorf("text stream is closed")
+	}
+	if (iomode == fsoForReading) != (s.iomode == fsoForReading) {
+		return fmt.Errorf("bad file mode")
+	}
+	return nil
+}
+
+//peek returns the next character without reading it, or -1 at the end of the stream
+func (s *fsoTextStream) peek() rune {
+	width := 1
+	if s.file.encoding == fileModeUTF16LE || s.file.encoding == fileModeUTF16BE {
+		width = 2
+	}
+	next, err := s.file.reader.Peek(width)
+	if err != nil || len(next) < width {
+		return -1
+	}
+	switch s.file.encoding {
+	case fileModeUTF16LE:
+		return rune(next[0]) | rune(next[1])<<8
+	case fileModeUTF16BE:
+		return rune(next[1]) | rune(next[0])<<8
+	}
+	return rune(next[0])
+}
@@ -0,0 +1,221 @@
+package autoit
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+//HostError can be returned by the methods of a host object to set @error and @extended to specific values
+type HostError struct {
+	Code     int   //Value of @error, 1 if left at 0
+	Extended int   //Value of @extended
+	Err      error //Underlying error, if any
+}
+
+func (e *HostError) Error() string {
+	if e.Err == nil {
+		return "host object error"
+	}
+	return e.Err.Error()
+}
+func (e *HostError) Unwrap() error {
+	return e.Err
+}
+
+//hostObject exposes a Go value to scripts, with its exported fields as properties and its methods as members
+type hostObject struct {
+	value reflect.Value
+}
+
+//NewHostObject wraps a Go value so scripts can use it as an object. Exported fields are properties and exported
+//methods can be called, with member names matched case-insensitively. Arguments and results are converted between
+//AutoIt and Go values, where slices and maps become arrays and maps and any other Go value becomes an object itself.
+//A non-nil error returned as the last result of a method sets @error, to the code of a *HostError or 1 otherwise.
+//It returns nil if value is nil.
+func NewHostObject(value interface{}) Object {
+	if object, ok := value.(Object); ok {
+		return object
+	}
+	v := reflect.ValueOf(value)
+	if !v.IsValid() {
+		return nil
+	}
+	if v.Kind() == reflect.Struct {
+		//Structs are copied behind a pointer so their fields can be set and pointer methods called
+		ptr := reflect.New(v.Type())
+		ptr.Elem().Set(v)
+		v = ptr
+	}
+	return &hostObject{value: v}
+}
+
+//AddObject wraps a Go value with NewHostObject and returns a handle to it, which can be stored with SetVariable.
+//It returns Null if value is nil.
+func (vm *AutoItVM) AddObject(value interface{}) *Token {
+	object := NewHostObject(value)
+	if object == nil {
+		return NewToken(tNULL, "Null")
+	}
+	return vm.AddHandle(object)
+}
+
+//ObjectName returns the name reported by ObjName
+func (h *hostObject) ObjectName() string {
+	goType := h.value.Type()
+	if goType.Kind() == reflect.Ptr {
+		goType = goType.Elem()
+	}
+	if goType.Name() == "" {
+		return goType.String()
+	}
+	return goType.Name()
+}
+
+//Get returns the value of an exported field, or calls a method that takes no arguments
+func (h *hostObject) Get(vm *AutoItVM, name string) (*Token, error) {
+	if field, ok := h.field(name); ok {
+		return vm.fromGo(field), nil
+	}
+	if _, ok := h.method(name); ok {
+		return h.Call(vm, name, nil)
+	}
+	return nil, errUnknownMember(h, name)
+}
+
+//Set sets the value of an exported field
+func (h *hostObject) Set(vm *AutoItVM, name string, args []*Token, value *Token) error {
+	field, ok := h.field(name)
+	if !ok {
+		return errUnknownMember(h, name)
+	}
+	if len(args) > 0 {
+		return errMemberArgs(h, name)
+	}
+	if !field.CanSet() {
+		return vm.Error("property %s of object %s is read-only", name, h.ObjectName())
+	}
+	converted, err := vm.toGo(value, field.Type())
+	if err != nil {
+		return vm.Error("can't set %s.%s: %v", h.ObjectName(), name, err)
+	}
+	field.Set(converted)
+	return nil
+}
+
+//Call calls an exported method
+func (h *hostObject) Call(vm *AutoItVM, name string, args []*Token) (*Token, error) {
+	method, ok := h.method(name)
+	if !ok {
+		if field, ok := h.field(name); ok && len(args) == 0 {
+			return vm.fromGo(field), nil
+		}
+		return nil, errUnknownMember(h, name)
+	}
+
+	methodType := method.Type()
+	required := methodType.NumIn()
+	if methodType.IsVariadic() {
+		required--
+	}
+	if len(args) < required || (len(args) > required && !methodType.IsVariadic()) {
+		return nil, errMemberArgs(h, name)
+	}
+	in := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		var argType reflect.Type
+		if i >= required {
+			argType = methodType.In(required).Elem()
+		} else {
+			argType = methodType.In(i)
+		}
+		converted, err := vm.toGo(arg, argType)
+		if err != nil {
+			return nil, vm.Error("bad argument %d for %s.%s: %v", i+1, h.ObjectName(), name, err)
+		}
+		in[i] = converted
+	}
+
+	vm.SetError(0)
+	vm.SetExtended(0)
+	out, err := callMethod(method, in)
+	if err != nil {
+		return nil, vm.Error("%s.%s: %v", h.ObjectName(), name, err)
+	}
+	if len(out) > 0 && methodType.Out(len(out)-1) == errorType {
+		if err, _ := out[len(out)-1].Interface().(error); err != nil {
+			code, extended := 1, 0
+			var hostErr *HostError
+			if errors.As(err, &hostErr) {
+				extended = hostErr.Extended
+				if hostErr.Code != 0 {
+					code = hostErr.Code
+				}
+			}
+			vm.SetError(code)
+			vm.SetExtended(extended)
+			return NewToken(tSTRING, ""), nil
+		}
+		out = out[:len(out)-1]
+	}
+
+	switch len(out) {
+	case 0:
+		return NewToken(tSTRING, ""), nil
+	case 1:
+		return vm.fromGo(out[0]), nil
+	}
+	//Methods with several results return them as an array
+	values := make([]*Token, len(out))
+	for i, result := range out {
+		values[i] = vm.fromGo(result)
+	}
+	return vm.NewArray(values), nil
+}
+
+//callMethod calls a method, returning a panic inside of it as an error so it can't bring down the host
+func callMethod(method reflect.Value, in []reflect.Value) (out []reflect.Value, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return method.Call(in), nil
+}
+
+//field returns an exported field of the wrapped struct by name
+func (h *hostObject) field(name string) (reflect.Value, bool) {
+	v := h.value
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}, false
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+	structField, ok := v.Type().FieldByNameFunc(func(fieldName string) bool {
+		return strings.EqualFold(fieldName, name)
+	})
+	if !ok || structField.PkgPath != "" {
+		return reflect.Value{}, false
+	}
+	field, err := v.FieldByIndexErr(structField.Index)
+	if err != nil {
+		return reflect.Value{}, false
+	}
+	return field, true
+}
+
+//method returns an exported method of the wrapped value by name
+func (h *hostObject) method(name string) (reflect.Value, bool) {
+	goType := h.value.Type()
+	for i := 0; i < goType.NumMethod(); i++ {
+		if strings.EqualFold(goType.Method(i).Name, name) {
+			return h.value.Method(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
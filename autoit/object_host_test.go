@@ -0,0 +1,63 @@
+package autoit
+
+import (
+	"testing"
+)
+
+type testInner struct {
+	X int
+}
+
+type testService struct {
+	Inner testInner
+	Name  string
+}
+
+func (s *testService) Total() int {
+	return s.Inner.X
+}
+
+//newHostTestVM returns a VM running script with svc stored in $svc
+func newHostTestVM(t *testing.T, script string, svc *testService) *AutoItVM {
+	t.Helper()
+	vm, err := NewAutoItScriptVM("object_host_test.au3", []byte(script+"\n"), nil)
+	if err != nil {
+		t.Fatalf("can't load script: %v", err)
+	}
+	vm.SetVariable("svc", vm.AddObject(svc))
+	return vm
+}
+
+func TestHostObjectNestedFields(t *testing.T) {
+	svc := &testService{}
+	vm := newHostTestVM(t, "$svc.Inner.X = 5\n$svc.Name = \"a\"\nConsoleWrite($svc.Inner.X & \" \" & $svc.Total() & \" \" & $svc.Name)", svc)
+	if err := vm.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if svc.Inner.X != 5 || svc.Name != "a" {
+		t.Errorf("svc = %+v, want Inner.X 5 and Name a", *svc)
+	}
+	if out := vm.Stdout(); out != "5 5 a" {
+		t.Errorf("output = %q, want %q", out, "5 5 a")
+	}
+}
+
+func TestHostObjectHandleReuse(t *testing.T) {
+	svc := &testService{Inner: testInner{X: 3}}
+	vm := newHostTestVM(t, "$a = $svc.Inner\n$b = $svc.Inner\n$n = $svc.Inner.X\n$n = $svc.Inner.X", svc)
+	if err := vm.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if a, b := vm.GetVariable("a"), vm.GetVariable("b"); a.Handle() != b.Handle() {
+		t.Errorf("reading a field twice returned different handles %s and %s", a.Handle(), b.Handle())
+	}
+	objects := 0
+	for _, handle := range vm.handles {
+		if _, ok := handle.(*hostObject); ok {
+			objects++
+		}
+	}
+	if objects != 2 {
+		t.Errorf("%d host objects, want 2", objects)
+	}
+}
@@ -0,0 +1,142 @@
+//go:build !windows
+// +build !windows
+
+package autoit
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+//languageIDs maps locales to the Windows language IDs returned by @OSLang
+var languageIDs = map[string]string{
+	"ar_SA": "0401", "cs_CZ": "0405", "da_DK": "0406", "de_DE": "0407", "el_GR": "0408",
+	"en_US": "0409", "es_ES": "0C0A", "fi_FI": "040B", "fr_FR": "040C", "he_IL": "040D",
+	"hu_HU": "040E", "it_IT": "0410", "ja_JP": "0411", "ko_KR": "0412", "nl_NL": "0413",
+	"nb_NO": "0414", "pl_PL": "0415", "pt_BR": "0416", "ro_RO": "0418", "ru_RU": "0419",
+	"sv_SE": "041D", "tr_TR": "041F", "uk_UA": "0422", "zh_CN": "0804", "de_CH": "0807",
+	"en_GB": "0809", "es_MX": "080A", "fr_BE": "080C", "nl_BE": "0813", "pt_PT": "0816",
+	"zh_TW": "0404", "de_AT": "0C07", "en_AU": "0C09", "fr_CA": "0C0C", "en_CA": "1009",
+}
+
+//specialDir returns the directory standing in for a Windows special folder, following the XDG base directories
+func specialDir(name string) string {
+	home, _ := os.UserHomeDir()
+	config, err := os.UserConfigDir()
+	if err != nil {
+		config = filepath.Join(home, ".config")
+	}
+
+	switch name {
+	case "home", "homepath":
+		return home
+	case "homedrive":
+		//Paths don't start with drives, so @HomeDrive & @HomePath is the home directory
+		return ""
+	case "appdata":
+		return config
+	case "localappdata":
+		if data := os.Getenv("XDG_DATA_HOME"); data != "" {
+			return data
+		}
+		if runtime.GOOS == "darwin" {
+			return config
+		}
+		return filepath.Join(home, ".local", "share")
+	case "desktop":
+		return xdgUserDir("XDG_DESKTOP_DIR", filepath.Join(home, "Desktop"))
+	case "documents":
+		return xdgUserDir("XDG_DOCUMENTS_DIR", filepath.Join(home, "Documents"))
+	case "programfiles":
+		return "/opt"
+	case "system":
+		return "/usr/bin"
+	case "windows":
+		return "/"
+	case "startup":
+		return filepath.Join(config, "autostart")
+	}
+	return ""
+}
+
+//xdgUserDir reads a directory from the XDG user-dirs.dirs file, falling back to the given directory
+func xdgUserDir(key, fallback string) string {
+	config, err := os.UserConfigDir()
+	if err != nil {
+		return fallback
+	}
+	dirs := readKeyValues(filepath.Join(config, "user-dirs.dirs"))
+	dir, ok := dirs[key]
+	if !ok || dir == "" {
+		return fallback
+	}
+	home, _ := os.UserHomeDir()
+	return strings.ReplaceAll(dir, "$HOME", home)
+}
+
+//osVersion returns the distribution and its version from os-release, such as UBUNTU_22.04
+func osVersion() string {
+	release := readKeyValues("/etc/os-release")
+	if len(release) == 0 {
+		release = readKeyValues("/usr/lib/os-release")
+	}
+	id := release["ID"]
+	if id == "" {
+		return strings.ToUpper(runtime.GOOS)
+	}
+	if release["VERSION_ID"] != "" {
+		id += "_" + release["VERSION_ID"]
+	}
+	return strings.ToUpper(id)
+}
+
+//osBuild returns the kernel release
+func osBuild() string {
+	release, err := os.ReadFile("/proc/sys/kernel/osrelease")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(release))
+}
+
+//osLang returns the Windows language ID of the locale used for messages
+func osLang() string {
+	for _, variable := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		locale := os.Getenv(variable)
+		if locale == "" {
+			continue
+		}
+		//Strip the encoding and modifier, as in en_US.UTF-8@euro
+		locale = strings.SplitN(strings.SplitN(locale, ".", 2)[0], "@", 2)[0]
+		if id, ok := languageIDs[locale]; ok {
+			return id
+		}
+		break
+	}
+	return languageIDs["en_US"]
+}
+
+//readKeyValues reads the KEY=value lines of a shell-style configuration file, removing quotes around values
+func readKeyValues(path string) map[string]string {
+	values := make(map[string]string)
+	file, err := os.Open(path)
+	if err != nil {
+		return values
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if parts := strings.SplitN(line, "=", 2); len(parts) == 2 {
+			values[parts[0]] = strings.Trim(parts[1], `"'`)
+		}
+	}
+	return values
+}
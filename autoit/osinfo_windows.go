@@ -0,0 +1,83 @@
+package autoit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+//osVersionInfo is the OSVERSIONINFOW structure filled by RtlGetVersion
+type osVersionInfo struct {
+	size, major, minor, build, platform uint32
+	servicePack                         [128]uint16
+}
+
+//specialDir returns the path of a Windows special folder
+func specialDir(name string) string {
+	switch name {
+	case "home":
+		return os.Getenv("USERPROFILE")
+	case "homedrive":
+		return os.Getenv("HOMEDRIVE")
+	case "homepath":
+		return os.Getenv("HOMEPATH")
+	case "appdata":
+		return os.Getenv("APPDATA")
+	case "localappdata":
+		return os.Getenv("LOCALAPPDATA")
+	case "desktop":
+		return filepath.Join(os.Getenv("USERPROFILE"), "Desktop")
+	case "documents":
+		return filepath.Join(os.Getenv("USERPROFILE"), "Documents")
+	case "programfiles":
+		return os.Getenv("ProgramFiles")
+	case "system":
+		return filepath.Join(os.Getenv("SystemRoot"), "System32")
+	case "windows":
+		return os.Getenv("SystemRoot")
+	case "startup":
+		return filepath.Join(os.Getenv("APPDATA"), "Microsoft", "Windows", "Start Menu", "Programs", "Startup")
+	}
+	return ""
+}
+
+//windowsVersion returns the version reported by RtlGetVersion, which isn't affected by compatibility shims
+func windowsVersion() osVersionInfo {
+	info := osVersionInfo{}
+	info.size = uint32(unsafe.Sizeof(info))
+	syscall.NewLazyDLL("ntdll.dll").NewProc("RtlGetVersion").Call(uintptr(unsafe.Pointer(&info)))
+	return info
+}
+
+//osVersion returns the Windows version in the form used by @OSVersion, such as WIN_10
+func osVersion() string {
+	info := windowsVersion()
+	switch {
+	case info.major == 10 && info.build >= 22000:
+		return "WIN_11"
+	case info.major == 10:
+		return "WIN_10"
+	case info.major == 6 && info.minor == 3:
+		return "WIN_81"
+	case info.major == 6 && info.minor == 2:
+		return "WIN_8"
+	case info.major == 6 && info.minor == 1:
+		return "WIN_7"
+	case info.major == 6 && info.minor == 0:
+		return "WIN_VISTA"
+	}
+	return fmt.Sprintf("WIN_%d.%d", info.major, info.minor)
+}
+
+//osBuild returns the Windows build number
+func osBuild() string {
+	return fmt.Sprint(windowsVersion().build)
+}
+
+//osLang returns the language ID of the user interface
+func osLang() string {
+	id, _, _ := syscall.NewLazyDLL("kernel32.dll").NewProc("GetUserDefaultUILanguage").Call()
+	return fmt.Sprintf("%04X", uint16(id))
+}
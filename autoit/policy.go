@@ -0,0 +1,208 @@
+package autoit
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+//Capability is a category of built-in functions a Policy can allow
+type Capability int
+
+const (
+	CapFileRead    Capability = 1 << iota //Reading files and directories, including INI files
+	CapFileWrite                          //Creating, changing and removing files and directories
+	CapNetwork                            //TCP, UDP and HTTP
+	CapProcess                            //Starting and closing processes
+	CapEnvironment                        //Reading and changing environment variables
+	CapDllCall                            //Calling native libraries and reading native memory
+	CapExit                               //Exiting the process with Exit, which otherwise only ends the script
+
+	CapAll = CapFileRead | CapFileWrite | CapNetwork | CapProcess | CapEnvironment | CapDllCall | CapExit
+)
+
+//ErrNotAllowed is matched by the runtime errors of calls denied by a Policy
+var ErrNotAllowed = errors.New("is not allowed by the policy")
+
+//errScriptExit ends the script when Exit isn't allowed to exit the process
+var errScriptExit = errors.New("script exited")
+
+//Policy restricts the built-in functions the scripts of a VM can call. Denied calls set @error to 1 and return 0,
+//or stop the script with a runtime error matching ErrNotAllowed if Fatal is set.
+type Policy struct {
+	Capabilities   Capability //Categories of built-in functions scripts can call, where functions outside of any category are always allowed
+	AllowFunctions []string   //Built-in functions scripts can call even when their category isn't allowed
+	DenyFunctions  []string   //Built-in functions scripts can't call even when their category is allowed
+	Paths          []string   //Directories and files the file functions can access, or anywhere if empty. Scripting.FileSystemObject isn't available when set.
+	Fatal          bool       //Denied calls stop the script instead of failing with @error
+}
+
+//builtinCapabilities holds the capabilities each built-in function needs
+var builtinCapabilities = map[string]Capability{
+	"filedelete": CapFileWrite, "fileflush": CapFileWrite, "filesetend": CapFileWrite,
+	"filegetencoding": CapFileRead, "fileread": CapFileRead, "filereadline": CapFileRead, "filereadtoarray": CapFileRead,
+	"filewrite": CapFileWrite, "filewriteline": CapFileWrite,
+	"filecopy": CapFileRead | CapFileWrite, "filemove": CapFileWrite, "fileexists": CapFileRead,
+	"filegetattrib": CapFileRead, "filegetsize": CapFileRead, "filegettime": CapFileRead,
+	"filesetattrib": CapFileWrite, "filesettime": CapFileWrite, "filefindfirstfile": CapFileRead,
+	"dircopy": CapFileRead | CapFileWrite, "dircreate": CapFileWrite, "dirgetsize": CapFileRead,
+	"dirmove": CapFileWrite, "dirremove": CapFileWrite,
+	"iniread": CapFileRead, "inireadsection": CapFileRead, "inireadsectionnames": CapFileRead,
+	"iniwrite": CapFileWrite, "inidelete": CapFileWrite, "inirenamesection": CapFileWrite, "iniwritesection": CapFileWrite,
+
+	"inetread": CapNetwork, "inetget": CapNetwork | CapFileWrite, "inetgetsize": CapNetwork,
+	"tcplisten": CapNetwork, "tcpaccept": CapNetwork, "tcpconnect": CapNetwork, "tcpsend": CapNetwork, "tcprecv": CapNetwork,
+	"tcpnametoip": CapNetwork, "udpbind": CapNetwork, "udpopen": CapNetwork, "udpsend": CapNetwork, "udprecv": CapNetwork,
+	"_winhttpopen": CapNetwork, "_winhttpconnect": CapNetwork, "_winhttpopenrequest": CapNetwork,
+	"_winhttpsendrequest": CapNetwork, "_winhttpwritedata": CapNetwork, "_winhttpreceiveresponse": CapNetwork,
+	"_winhttpreaddata": CapNetwork, "_winhttpquerydataavailable": CapNetwork,
+	"_winhttpsimplerequest": CapNetwork, "_winhttpsimplesslrequest": CapNetwork,
+
+	"run": CapProcess, "runwait": CapProcess, "shellexecute": CapProcess, "shellexecutewait": CapProcess,
+	"processclose": CapProcess, "processsetpriority": CapProcess,
+
+	"envget": CapEnvironment, "envset": CapEnvironment, "envupdate": CapEnvironment,
+
+	"dllopen": CapDllCall, "dllcall": CapDllCall,
+}
+
+//builtinPaths holds the arguments of built-in functions that name files or directories
+var builtinPaths = map[string][]string{
+	"filedelete": {"filename"}, "filegetencoding": {"file"}, "fileopen": {"filename"},
+	"fileread": {"file"}, "filereadline": {"file"}, "filereadtoarray": {"file"},
+	"filewrite": {"file"}, "filewriteline": {"file"},
+	"filecopy": {"source", "dest"}, "filemove": {"source", "dest"}, "fileexists": {"path"},
+	"filegetattrib": {"filename"}, "filegetsize": {"filename"}, "filegettime": {"filename"},
+	"filesetattrib": {"filepattern"}, "filesettime": {"filepattern"}, "filefindfirstfile": {"filename"},
+	"dircopy": {"source", "dest"}, "dircreate": {"path"}, "dirgetsize": {"path"},
+	"dirmove": {"source", "dest"}, "dirremove": {"path"},
+	"iniread": {"filename"}, "inireadsection": {"filename"}, "inireadsectionnames": {"filename"},
+	"iniwrite": {"filename"}, "inidelete": {"filename"}, "inirenamesection": {"filename"}, "iniwritesection": {"filename"},
+	"inetget": {"filename"},
+}
+
+//allows returns true if the policy allows all of the given capabilities
+func (p *Policy) allows(capabilities Capability) bool {
+	return p == nil || p.Capabilities&capabilities == capabilities
+}
+
+//check returns an error if the policy denies calling a built-in function with the given arguments
+func (p *Policy) check(name string, args map[string]*Token) error {
+	if p == nil {
+		return nil
+	}
+	name = strings.ToLower(name)
+	for _, denied := range p.DenyFunctions {
+		if strings.EqualFold(denied, name) {
+			return fmt.Errorf("%s %w", name, ErrNotAllowed)
+		}
+	}
+
+	allowed := false
+	for _, function := range p.AllowFunctions {
+		if strings.EqualFold(function, name) {
+			allowed = true
+			break
+		}
+	}
+	if !allowed && !p.allows(p.needs(name, args)) {
+		return fmt.Errorf("%s %w", name, ErrNotAllowed)
+	}
+
+	if len(p.Paths) == 0 {
+		return nil
+	}
+	if name == "objcreate" && isFileSystemObject(args["classname"]) {
+		//Its members can't be limited to the allowed paths
+		return fmt.Errorf("Scripting.FileSystemObject %w", ErrNotAllowed)
+	}
+	for _, argName := range builtinPaths[name] {
+		//Files can also be given by the handles FileOpen returns, which were checked when they were opened
+		if arg := args[argName]; arg != nil && arg.Type != tHANDLE && arg.Type != tDEFAULT {
+			if !p.allowsPath(arg.String()) {
+				return fmt.Errorf("path %s %w", arg.String(), ErrNotAllowed)
+			}
+		}
+	}
+	return nil
+}
+
+//checkInclude returns an error if the policy denies reading a script included with #include
+func (p *Policy) checkInclude(path string) error {
+	if p == nil {
+		return nil
+	}
+	if !p.allows(CapFileRead) {
+		return fmt.Errorf("#include %w", ErrNotAllowed)
+	}
+	if len(p.Paths) > 0 && !p.allowsPath(path) {
+		return fmt.Errorf("path %s %w", path, ErrNotAllowed)
+	}
+	return nil
+}
+
+//needs returns the capabilities calling a built-in function with the given arguments needs
+func (p *Policy) needs(name string, args map[string]*Token) Capability {
+	switch name {
+	case "fileopen":
+		if mode := args["mode"]; mode != nil && intValue(mode)&(fileModeAppend|fileModeOverwrite) != 0 {
+			return CapFileWrite
+		}
+		return CapFileRead
+	case "dllstructcreate":
+		//Structs created at a pointer read and write native memory
+		if pointer := args["pointer"]; pointer != nil && pointer.Type != tDEFAULT {
+			return CapDllCall
+		}
+	case "objcreate":
+		if isFileSystemObject(args["classname"]) {
+			return CapFileRead | CapFileWrite
+		}
+	}
+	return builtinCapabilities[name]
+}
+
+//isFileSystemObject returns true if an ObjCreate class name creates a Scripting.FileSystemObject
+func isFileSystemObject(classname *Token) bool {
+	return classname != nil && strings.EqualFold(classname.String(), "Scripting.FileSystemObject")
+}
+
+//allowsPath returns true if a path is inside one of the allowed paths
+func (p *Policy) allowsPath(path string) bool {
+	target, err := resolvePath(path)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range p.Paths {
+		root, err := resolvePath(allowed)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(root, target)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+//resolvePath returns the absolute path of a file following any symbolic links, even if the file doesn't exist yet
+func resolvePath(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved, nil
+	}
+	parent := filepath.Dir(path)
+	if parent == path {
+		return path, nil
+	}
+	dir, err := resolvePath(parent)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, filepath.Base(path)), nil
+}
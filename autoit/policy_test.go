@@ -0,0 +1,265 @@
+package autoit
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+//runScript runs a script with the given policy, returning what it wrote to the console
+func runScript(t *testing.T, script string, policy *Policy) (string, error) {
+	t.Helper()
+	vm, err := NewAutoItScriptVM("policy_test.au3", []byte(script+"\n"), nil)
+	if err != nil {
+		t.Fatalf("can't load script: %v", err)
+	}
+	vm.Policy = policy
+	err = vm.Run()
+	return vm.Stdout(), err
+}
+
+//quote returns a path as a string literal for a script
+func quote(path string) string {
+	return "\"" + strings.ReplaceAll(path, "\"", "\"\"") + "\""
+}
+
+func TestPolicyCapabilities(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(file, []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		script       string
+		capabilities Capability
+		allow, deny  []string
+		denied       bool
+	}{
+		{"no policy function", "$x = String(1)", 0, nil, nil, false},
+		{"file read denied", "$x = FileExists(" + quote(file) + ")", CapFileWrite, nil, nil, true},
+		{"file read allowed", "$x = FileExists(" + quote(file) + ")", CapFileRead, nil, nil, false},
+		{"file write denied", "$x = DirCreate(" + quote(filepath.Join(dir, "new")) + ")", CapFileRead, nil, nil, true},
+		{"file write allowed", "$x = DirCreate(" + quote(filepath.Join(dir, "new")) + ")", CapFileWrite, nil, nil, false},
+		{"file copy needs both", "$x = FileCopy(" + quote(file) + ", " + quote(filepath.Join(dir, "b.txt")) + ")", CapFileWrite, nil, nil, true},
+		{"network denied", "$x = TCPNameToIP(\"127.0.0.1\")", CapAll &^ CapNetwork, nil, nil, true},
+		{"network allowed", "$x = TCPNameToIP(\"127.0.0.1\")", CapNetwork, nil, nil, false},
+		{"process denied", "$x = ProcessClose(\"policy-test-none\")", CapAll &^ CapProcess, nil, nil, true},
+		{"process allowed", "$x = ProcessClose(\"policy-test-none\")", CapProcess, nil, nil, false},
+		{"environment denied", "$x = EnvGet(\"PATH\")", CapAll &^ CapEnvironment, nil, nil, true},
+		{"environment allowed", "$x = EnvGet(\"PATH\")", CapEnvironment, nil, nil, false},
+		{"dll call denied", "$x = DllOpen(\"policy-test-none\")", CapAll &^ CapDllCall, nil, nil, true},
+		{"dll call allowed", "$x = DllOpen(\"policy-test-none\")", CapDllCall, nil, nil, false},
+		{"allow overrides capability", "$x = EnvGet(\"PATH\")", 0, []string{"EnvGet"}, nil, false},
+		{"allow only listed function", "$x = EnvSet(\"POLICY_TEST\", \"1\")", 0, []string{"EnvGet"}, nil, true},
+		{"deny overrides capability", "$x = EnvGet(\"PATH\")", CapAll, nil, []string{"envget"}, true},
+		{"deny overrides allow", "$x = EnvGet(\"PATH\")", CapAll, []string{"EnvGet"}, []string{"ENVGET"}, true},
+		{"deny function outside of categories", "$x = String(1)", CapAll, nil, []string{"String"}, true},
+		{"file open read", "$x = FileOpen(" + quote(file) + ", 0)", CapFileRead, nil, nil, false},
+		{"file open append", "$x = FileOpen(" + quote(file) + ", 1)", CapFileRead, nil, nil, true},
+		{"file open overwrite", "$x = FileOpen(" + quote(file) + ", 2)", CapFileRead, nil, nil, true},
+		{"file open overwrite allowed", "$x = FileOpen(" + quote(file) + ", 2)", CapFileWrite, nil, nil, false},
+		{"struct", "$x = DllStructCreate(\"int\")", 0, nil, nil, false},
+		{"struct at pointer denied", "$s = DllStructCreate(\"int\")\n$x = DllStructCreate(\"int\", DllStructGetPtr($s))", CapAll &^ CapDllCall, nil, nil, true},
+		{"struct at pointer allowed", "$s = DllStructCreate(\"int\")\n$x = DllStructCreate(\"int\", DllStructGetPtr($s))", CapDllCall, nil, nil, false},
+		{"file system object denied", "$x = ObjCreate(\"Scripting.FileSystemObject\")", CapFileRead, nil, nil, true},
+		{"file system object allowed", "$x = ObjCreate(\"Scripting.FileSystemObject\")", CapFileRead | CapFileWrite, nil, nil, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			policy := &Policy{Capabilities: test.capabilities, AllowFunctions: test.allow, DenyFunctions: test.deny, Fatal: true}
+			_, err := runScript(t, test.script, policy)
+			if test.denied && !errors.Is(err, ErrNotAllowed) {
+				t.Errorf("error = %v, want ErrNotAllowed", err)
+			} else if !test.denied && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestPolicyPaths(t *testing.T) {
+	root := t.TempDir()
+	allowed := filepath.Join(root, "allowed")
+	if err := os.Mkdir(allowed, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{filepath.Join(allowed, "a.txt"), filepath.Join(root, "b.txt")} {
+		if err := os.WriteFile(file, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	symlinks := true
+	if err := os.Symlink(filepath.Join(root, "b.txt"), filepath.Join(allowed, "link.txt")); err != nil {
+		symlinks = false
+	}
+	if err := os.Symlink(root, filepath.Join(allowed, "linkdir")); err != nil {
+		symlinks = false
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		denied  bool
+		symlink bool
+	}{
+		{"inside", filepath.Join(allowed, "a.txt"), false, false},
+		{"allowed directory", allowed, false, false},
+		{"new file inside", filepath.Join(allowed, "new", "c.txt"), false, false},
+		{"outside", filepath.Join(root, "b.txt"), true, false},
+		{"parent", root, true, false},
+		{"dot dot escape", filepath.Join(allowed, "..", "b.txt"), true, false},
+		{"dot dot inside", filepath.Join(allowed, "new", "..", "a.txt"), false, false},
+		{"unclean dot dot escape", allowed + string(filepath.Separator) + ".." + string(filepath.Separator) + "b.txt", true, false},
+		{"prefix of allowed directory", allowed + "2", true, false},
+		{"symlinked file escape", filepath.Join(allowed, "link.txt"), true, true},
+		{"symlinked directory escape", filepath.Join(allowed, "linkdir", "b.txt"), true, true},
+		{"new file in symlinked directory", filepath.Join(allowed, "linkdir", "new.txt"), true, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.symlink && !symlinks {
+				t.Skip("symbolic links aren't supported")
+			}
+			policy := &Policy{Capabilities: CapFileRead | CapFileWrite, Paths: []string{allowed}, Fatal: true}
+			_, err := runScript(t, "$x = FileExists("+quote(test.path)+")", policy)
+			if test.denied && !errors.Is(err, ErrNotAllowed) {
+				t.Errorf("error = %v, want ErrNotAllowed", err)
+			} else if !test.denied && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+
+	t.Run("copy to outside", func(t *testing.T) {
+		policy := &Policy{Capabilities: CapFileRead | CapFileWrite, Paths: []string{allowed}, Fatal: true}
+		script := "$x = FileCopy(" + quote(filepath.Join(allowed, "a.txt")) + ", " + quote(filepath.Join(root, "c.txt")) + ")"
+		if _, err := runScript(t, script, policy); !errors.Is(err, ErrNotAllowed) {
+			t.Errorf("error = %v, want ErrNotAllowed", err)
+		}
+	})
+	t.Run("file system object", func(t *testing.T) {
+		policy := &Policy{Capabilities: CapAll, Paths: []string{allowed}, Fatal: true}
+		if _, err := runScript(t, "$x = ObjCreate(\"Scripting.FileSystemObject\")", policy); !errors.Is(err, ErrNotAllowed) {
+			t.Errorf("error = %v, want ErrNotAllowed", err)
+		}
+	})
+}
+
+func TestPolicyErrorMode(t *testing.T) {
+	script := "$x = EnvGet(\"PATH\")\nConsoleWrite(@error & \" \" & $x)\nConsoleWrite(\" done\")"
+	out, err := runScript(t, script, &Policy{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "1 0 done" {
+		t.Errorf("output = %q, want %q", out, "1 0 done")
+	}
+
+	out, err = runScript(t, script, &Policy{Fatal: true})
+	if !errors.Is(err, ErrNotAllowed) {
+		t.Errorf("error = %v, want ErrNotAllowed", err)
+	}
+	if out != "" {
+		t.Errorf("output = %q, want nothing", out)
+	}
+}
+
+func TestPolicyExit(t *testing.T) {
+	script := "ConsoleWrite(\"before\")\nExit\nConsoleWrite(\"after\")"
+	out, err := runScript(t, script, &Policy{Capabilities: CapAll &^ CapExit})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "before" {
+		t.Errorf("output = %q, want %q", out, "before")
+	}
+
+	out, err = runScript(t, script, &Policy{Fatal: true})
+	if !errors.Is(err, ErrNotAllowed) {
+		t.Errorf("error = %v, want ErrNotAllowed", err)
+	}
+	if out != "before" {
+		t.Errorf("output = %q, want %q", out, "before")
+	}
+}
+
+func TestPolicyInclude(t *testing.T) {
+	root := t.TempDir()
+	allowed := filepath.Join(root, "allowed")
+	if err := os.Mkdir(allowed, 0755); err != nil {
+		t.Fatal(err)
+	}
+	include := filepath.Join(allowed, "include.au3")
+	if err := os.WriteFile(include, []byte("ConsoleWrite(\"included\")\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	secret := filepath.Join(root, "secret.txt")
+	if err := os.WriteFile(secret, []byte("root_password\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		policy *Policy
+		denied bool
+	}{
+		{"no policy", include, nil, false},
+		{"inside", include, &Policy{Capabilities: CapFileRead, Paths: []string{allowed}}, false},
+		{"outside", secret, &Policy{Capabilities: CapFileRead, Paths: []string{allowed}}, true},
+		{"dot dot escape", filepath.Join(allowed, "..", "secret.txt"), &Policy{Capabilities: CapFileRead, Paths: []string{allowed}}, true},
+		{"file read denied", include, &Policy{}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			//The include isn't the first line, where the preprocessor can't remove it
+			out, err := runScript(t, "\n#include "+quote(test.path), test.policy)
+			if test.denied {
+				if !errors.Is(err, ErrNotAllowed) {
+					t.Errorf("error = %v, want ErrNotAllowed", err)
+				}
+				if err != nil && strings.Contains(err.Error(), "root_password") {
+					t.Errorf("error %q leaks the included file", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if out != "included" {
+				t.Errorf("output = %q, want %q", out, "included")
+			}
+		})
+	}
+}
+
+func TestPolicyBuiltins(t *testing.T) {
+	for name := range builtinCapabilities {
+		if _, ok := stdFunctions[name]; !ok {
+			t.Errorf("capability of unknown function %s", name)
+		}
+	}
+	for name, argNames := range builtinPaths {
+		function, ok := stdFunctions[name]
+		if !ok {
+			t.Errorf("paths of unknown function %s", name)
+			continue
+		}
+		for _, argName := range argNames {
+			found := false
+			for _, arg := range function.Args {
+				if arg.Name == argName {
+					found = true
+					break
+				}
+			}
+			if !found {
+				t.Errorf("%s has no argument %s", name, argName)
+			}
+		}
+	}
+}
@@ -54,14 +54,18 @@ func (vm *AutoItVM) PreprocessIncludes() error {
 				if includeFile.Type != tSTRING {
 					return vm.Error("preprocess: expected string containing path to include")
 				}
+				if err := vm.Policy.checkInclude(includeFile.String()); err != nil {
+					return vm.Error("preprocess: %w", err)
+				}
 				includeScript, err := os.ReadFile(includeFile.String())
 				if err != nil {
-					return err
+					return vm.Error("preprocess: can't read include %s", includeFile.String())
 				}
 				includeLexer := NewLexer(includeScript)
 				includeTokens, err := includeLexer.GetTokens()
 				if err != nil {
-					return err
+					//The error would quote the file, which might not be a script at all
+					return vm.Error("preprocess: include %s is not a valid script", includeFile.String())
 				}
 				includeTokens = append(includeTokens, NewToken(tEOL, ""))
 				vm.tokens = append(vm.tokens[:vm.pos], append(includeTokens, vm.tokens[vm.pos:]...)...)
@@ -426,6 +430,12 @@ func (vm *AutoItVM) PreprocessFuncCalls() error {
 			if callErr != nil {
 				return callErr
 			}
+		case tPROPERTY:
+			vm.Move(-1)
+			callErr := vm.PreprocessMethodCall()
+			if callErr != nil {
+				return callErr
+			}
 		case tFUNC:
 			vm.Log("preprocess: skipped definition of func %s", vm.Token().String())
 			vm.Move(1)
@@ -447,7 +457,7 @@ func (vm *AutoItVM) PreprocessFuncCall() error {
 	}
 
 	isStd := false
-	if _, exists := stdFunctions[strings.ToLower(tCall.String())]; exists {
+	if _, exists := vm.builtin(tCall.String()); exists {
 		vm.Log("preprocess: setting call to std")
 		vm.SetToken(startPos, NewToken(tCALL, tCall.String()))
 		isStd = true
@@ -465,6 +475,61 @@ func (vm *AutoItVM) PreprocessFuncCall() error {
 		return nil
 	}
 
+	callBlocks, err := vm.PreprocessCallBlocks()
+	if err != nil {
+		return err
+	}
+
+	functionCall := &FunctionCall{Name: tCall.String(), Block: callBlocks}
+	tHandle := vm.AddHandle(functionCall)
+	if isStd {
+		vm.SetToken(startPos, NewToken(tCALL, tHandle.Handle()))
+	} else {
+		vm.SetToken(startPos, NewToken(tUDF, tHandle.Handle()))
+	}
+	vm.Log("preprocess: call preloaded successfully: %s -> %s = %v", tCall.String(), tHandle.String(), callBlocks)
+
+	endPos := vm.GetPos()
+	if endPos >= len(vm.tokens) {
+		//vm.Log("call end pos greater than end")
+	}
+	vm.Log("call end pos: %d %v", endPos, vm.GetToken(endPos))
+	vm.RemoveTokens(startPos+1, endPos)
+	return nil
+}
+
+//PreprocessMethodCall reads and processes an object method call, leaving properties without a call block as they are
+func (vm *AutoItVM) PreprocessMethodCall() error {
+	startPos := vm.GetPos()
+
+	tProperty := vm.ReadToken()
+	if tProperty.Type != tPROPERTY {
+		return vm.Error("preprocessor: expected object member, instead got: %v", tProperty)
+	}
+
+	tStart := vm.ReadToken()
+	if tStart == nil {
+		return nil
+	}
+	if tStart.Type != tLEFTPAREN {
+		vm.Move(-1)
+		return nil
+	}
+
+	callBlocks, err := vm.PreprocessCallBlocks()
+	if err != nil {
+		return err
+	}
+
+	tHandle := vm.AddHandle(&FunctionCall{Name: tProperty.String(), Block: callBlocks})
+	vm.SetToken(startPos, NewToken(tMETHOD, tHandle.Handle()))
+	vm.Log("preprocess: method call preloaded successfully: %s -> %s = %v", tProperty.String(), tHandle.String(), callBlocks)
+	vm.RemoveTokens(startPos+1, vm.GetPos())
+	return nil
+}
+
+//PreprocessCallBlocks reads the argument blocks of a call up to its closing parenthesis
+func (vm *AutoItVM) PreprocessCallBlocks() ([][]*Token, error) {
 	callBlocks := make([][]*Token, 0)
 	callBlock := make([]*Token, 0)
 	depth := 0
@@ -483,12 +548,19 @@ func (vm *AutoItVM) PreprocessFuncCall() error {
 			vm.Move(-1)
 			callErr := vm.PreprocessFuncCall()
 			if callErr != nil {
-				return callErr
+				return nil, callErr
+			}
+			callBlock = append(callBlock, vm.GetToken(vm.GetPos()-1))
+		case tPROPERTY:
+			vm.Move(-1)
+			callErr := vm.PreprocessMethodCall()
+			if callErr != nil {
+				return nil, callErr
 			}
 			callBlock = append(callBlock, vm.GetToken(vm.GetPos()-1))
 		case tSEPARATOR:
 			if depth > 0 {
-				return vm.Error("preprocessor: unexpected separator in nested func call block: %v", blockToken)
+				return nil, vm.Error("preprocessor: unexpected separator in nested func call block: %v", blockToken)
 			}
 			callBlocks = append(callBlocks, callBlock)
 			callBlock = make([]*Token, 0)
@@ -507,21 +579,5 @@ func (vm *AutoItVM) PreprocessFuncCall() error {
 	if len(callBlock) > 0 {
 		callBlocks = append(callBlocks, callBlock)
 	}
-
-	functionCall := &FunctionCall{Name: tCall.String(), Block: callBlocks}
-	tHandle := vm.AddHandle(functionCall)
-	if isStd {
-		vm.SetToken(startPos, NewToken(tCALL, tHandle.Handle()))
-	} else {
-		vm.SetToken(startPos, NewToken(tUDF, tHandle.Handle()))
-	}
-	vm.Log("preprocess: call preloaded successfully: %s -> %s = %v", tCall.String(), tHandle.String(), callBlocks)
-
-	endPos := vm.GetPos()
-	if endPos >= len(vm.tokens) {
-		//vm.Log("call end pos greater than end")
-	}
-	vm.Log("call end pos: %d %v", endPos, vm.GetToken(endPos))
-	vm.RemoveTokens(startPos+1, endPos)
-	return nil
+	return callBlocks, nil
 }
\ No newline at end of file
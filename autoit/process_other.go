@@ -0,0 +1,71 @@
+//go:build !windows
+// +build !windows
+
+package autoit
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"syscall"
+)
+
+//comSpec returns the command interpreter used by @ComSpec
+func comSpec() string {
+	return "/bin/sh"
+}
+
+//shellCommand prepares a command line to be run by the shell, translating "@ComSpec /c" so scripts written
+//for Windows work as is
+func shellCommand(program string) (*exec.Cmd, error) {
+	for _, prefix := range []string{comSpec() + " /c ", comSpec() + " /k "} {
+		if len(program) >= len(prefix) && strings.EqualFold(program[:len(prefix)], prefix) {
+			program = program[len(prefix):]
+			break
+		}
+	}
+	return exec.Command(comSpec(), "-c", program), nil
+}
+
+//shellExecuteCommand prepares a command that opens a file with the handler for the given verb
+func shellExecuteCommand(filename, parameters, verb string) (*exec.Cmd, error) {
+	//Executables are run directly when opening them
+	if verb == "" || strings.EqualFold(verb, "open") {
+		if path, err := exec.LookPath(filename); err == nil {
+			if stat, err := os.Stat(path); err == nil && !stat.IsDir() && stat.Mode()&0111 != 0 {
+				return exec.Command(comSpec(), "-c", shellQuote(path)+" "+parameters), nil
+			}
+		}
+	}
+
+	switch strings.ToLower(verb) {
+	case "edit":
+		for _, editor := range []string{os.Getenv("VISUAL"), os.Getenv("EDITOR")} {
+			if editor != "" {
+				return exec.Command(comSpec(), "-c", editor+" "+shellQuote(filename)), nil
+			}
+		}
+	case "print":
+		return exec.Command("lp", filename), nil
+	}
+
+	if runtime.GOOS == "darwin" {
+		return exec.Command("open", filename), nil
+	}
+	return exec.Command("xdg-open", filename), nil
+}
+
+//shellQuote quotes a string as a single shell argument
+func shellQuote(str string) string {
+	return "'" + strings.ReplaceAll(str, "'", `'\''`) + "'"
+}
+
+//processRunning determines whether a process with the given PID exists
+func processRunning(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
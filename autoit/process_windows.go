@@ -0,0 +1,53 @@
+package autoit
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+//comSpec returns the command interpreter used by @ComSpec
+func comSpec() string {
+	if comSpec := os.Getenv("ComSpec"); comSpec != "" {
+		return comSpec
+	}
+	return `C:\Windows\system32\cmd.exe`
+}
+
+//shellCommand prepares a command line to be run as given, the same way CreateProcess would
+func shellCommand(program string) (*exec.Cmd, error) {
+	//The program is the first argument, which may be quoted
+	name := program
+	if strings.HasPrefix(name, `"`) {
+		if end := strings.Index(name[1:], `"`); end >= 0 {
+			name = name[1 : end+1]
+		}
+	} else if space := strings.Index(name, " "); space >= 0 {
+		name = name[:space]
+	}
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(path)
+	cmd.SysProcAttr = &syscall.SysProcAttr{CmdLine: program}
+	return cmd, nil
+}
+
+//shellExecuteCommand prepares a command that opens a file with the handler for the given verb
+func shellExecuteCommand(filename, parameters, verb string) (*exec.Cmd, error) {
+	//start only knows the default verb, so every file is opened
+	return shellCommand(comSpec() + ` /c start "" /wait "` + filename + `" ` + parameters)
+}
+
+//processRunning determines whether a process with the given PID exists
+func processRunning(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	process.Release()
+	return true
+}
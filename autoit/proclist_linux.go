@@ -0,0 +1,103 @@
+package autoit
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+//processInfo holds the name and PID of a running process
+type processInfo struct {
+	name string
+	pid  int
+}
+
+//listProcesses returns every running process found in /proc
+func listProcesses() ([]processInfo, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+	processes := make([]processInfo, 0)
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		comm, err := os.ReadFile(filepath.Join("/proc", entry.Name(), "comm"))
+		if err != nil {
+			//The process exited while listing
+			continue
+		}
+		name := strings.TrimSpace(string(comm))
+
+		//Names are cut off at 15 characters, so prefer the program from the command line when it's longer
+		if len(name) == 15 {
+			if cmdline, err := os.ReadFile(filepath.Join("/proc", entry.Name(), "cmdline")); err == nil {
+				program := filepath.Base(string(bytes.SplitN(cmdline, []byte{0}, 2)[0]))
+				if strings.HasPrefix(program, name) {
+					name = program
+				}
+			}
+		}
+		processes = append(processes, processInfo{name: name, pid: pid})
+	}
+	return processes, nil
+}
+
+//setProcessPriority sets the niceness of a process from an AutoIt priority class
+func setProcessPriority(pid, priority int) error {
+	nice := []int{19, 10, 0, -5, -10, -20}[priority]
+	return syscall.Setpriority(syscall.PRIO_PROCESS, pid, nice)
+}
+
+//processMemoryStats returns the resident and peak resident memory of a process in bytes
+func processMemoryStats(pid int) ([]int64, error) {
+	status, err := procFields(pid, "status", ":")
+	if err != nil {
+		return nil, err
+	}
+	return []int64{procKB(status["VmRSS"]), procKB(status["VmHWM"])}, nil
+}
+
+//processIOStats returns the read, write and other operation counts of a process followed by the bytes transferred by each
+func processIOStats(pid int) ([]int64, error) {
+	io, err := procFields(pid, "io", ":")
+	if err != nil {
+		return nil, err
+	}
+	values := make([]int64, 0)
+	for _, field := range []string{"syscr", "syscw", "", "rchar", "wchar", ""} {
+		value, _ := strconv.ParseInt(strings.TrimSpace(io[field]), 10, 64)
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+//procFields reads the name and value pairs from a file describing a process in /proc
+func procFields(pid int, file, separator string) (map[string]string, error) {
+	osFile, err := os.Open(filepath.Join("/proc", strconv.Itoa(pid), file))
+	if err != nil {
+		return nil, err
+	}
+	defer osFile.Close()
+
+	fields := make(map[string]string)
+	scanner := bufio.NewScanner(osFile)
+	for scanner.Scan() {
+		if parts := strings.SplitN(scanner.Text(), separator, 2); len(parts) == 2 {
+			fields[parts[0]] = parts[1]
+		}
+	}
+	return fields, scanner.Err()
+}
+
+//procKB converts a size such as "1024 kB" into bytes
+func procKB(size string) int64 {
+	value, _ := strconv.ParseInt(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(size), "kB")), 10, 64)
+	return value * 1024
+}
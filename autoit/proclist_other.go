@@ -0,0 +1,37 @@
+//go:build !linux
+// +build !linux
+
+package autoit
+
+import (
+	"errors"
+)
+
+//processInfo holds the name and PID of a running process
+type processInfo struct {
+	name string
+	pid  int
+}
+
+//errProcessUnsupported is returned when processes can't be inspected on this platform
+var errProcessUnsupported = errors.New("process inspection is not supported on this platform")
+
+//listProcesses returns every running process
+func listProcesses() ([]processInfo, error) {
+	return nil, errProcessUnsupported
+}
+
+//setProcessPriority sets the priority of a process from an AutoIt priority class
+func setProcessPriority(pid, priority int) error {
+	return errProcessUnsupported
+}
+
+//processMemoryStats returns the working set and peak working set of a process in bytes
+func processMemoryStats(pid int) ([]int64, error) {
+	return nil, errProcessUnsupported
+}
+
+//processIOStats returns the read, write and other operation counts of a process followed by the bytes transferred by each
+func processIOStats(pid int) ([]int64, error) {
+	return nil, errProcessUnsupported
+}
@@ -1,9 +1,13 @@
 package autoit
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 	"path/filepath"
@@ -14,16 +18,22 @@ import (
 type AutoItVM struct {
 	//Runtime configuration
 	Logger bool
+	NetInterfaces NetInterfaceProvider //Lists the addresses used by @IPAddress1-4, or the system interfaces if nil
+	Limits Limits //Restricts the resources the script can use
+	Policy *Policy //Restricts the built-in functions the script can call, or allows all of them if nil
 	
 	//Script trackers
 	scriptPath string
 	tokens []*Token
 	funcs map[string]*Function
+	builtins map[string]*Function
 	pos int
 	preprocessed bool
 	skipPreprocess bool
 
 	//Runtime and memory
+	ctx context.Context
+	usage *vmUsage
 	running bool
 	suspended bool
 	error int
@@ -34,9 +44,13 @@ type AutoItVM struct {
 	numParams int
 	vars map[string]*Token
 	handles map[string]interface{}
+	hostHandles map[hostKey]string
 	parentScope *AutoItVM
 	stdout, stderr string
 	ranIfStatement bool
+	random *rand.Rand
+	processes map[int]*childProcess
+	httpConfig *httpConfig
 }
 
 func NewAutoItScriptVM(scriptPath string, script []byte, parentScope *AutoItVM) (*AutoItVM, error) {
@@ -56,9 +70,15 @@ func NewAutoItScriptVM(scriptPath string, script []byte, parentScope *AutoItVM)
 		tokens: tokens,
 		parentScope: parentScope,
 		funcs: make(map[string]*Function),
+		builtins: make(map[string]*Function),
 		vars: make(map[string]*Token),
 		handles: make(map[string]interface{}, 0),
+		hostHandles: make(map[hostKey]string),
+		processes: make(map[int]*childProcess),
+		httpConfig: &httpConfig{userAgent: defaultUserAgent},
 		returnValue: NewToken(tNUMBER, 0),
+		usage: &vmUsage{},
+		random: rand.New(rand.NewSource(time.Now().UnixNano())),
 		//Logger: true,
 	}, nil
 }
@@ -74,28 +94,69 @@ func NewAutoItTokenVM(scriptPath string, tokens []*Token, parentScope *AutoItVM)
 		tokens: tokens,
 		parentScope: parentScope,
 		funcs: make(map[string]*Function),
+		builtins: make(map[string]*Function),
 		vars: make(map[string]*Token),
 		handles: make(map[string]interface{}, 0),
+		hostHandles: make(map[hostKey]string),
+		processes: make(map[int]*childProcess),
+		httpConfig: &httpConfig{userAgent: defaultUserAgent},
 		returnValue: NewToken(tNUMBER, 0),
+		usage: &vmUsage{},
+		random: rand.New(rand.NewSource(time.Now().UnixNano())),
 	}, nil
 }
 
+//ErrCancelled is returned by RunContext when the script was stopped because its context was done
+var ErrCancelled = errors.New("script execution cancelled")
+
+//RunContext runs the script like Run, stopping at the next statement or inside a blocking function such as Sleep,
+//InetRead or RunWait once ctx is done. The error returned then matches both ErrCancelled and the error of ctx.
+func (vm *AutoItVM) RunContext(ctx context.Context) error {
+	vm.ctx = ctx
+	defer func() {
+		vm.ctx = nil
+	}()
+
+	err := vm.Run()
+	if err != nil && ctx.Err() != nil {
+		//Errors from inside functions only carry the message of the cancellation, so it's reported again
+		return fmt.Errorf("%w: %w", ErrCancelled, ctx.Err())
+	}
+	return err
+}
+
+//context returns the context the script is running with
+func (vm *AutoItVM) context() context.Context {
+	if vm.ctx == nil {
+		return context.Background()
+	}
+	return vm.ctx
+}
+
+//cancelled returns an error matching ErrCancelled if the context the script is running with is done
+func (vm *AutoItVM) cancelled() error {
+	if vm.ctx == nil || vm.ctx.Err() == nil {
+		return nil
+	}
+	return fmt.Errorf("%w: %w", ErrCancelled, vm.ctx.Err())
+}
+
 func (vm *AutoItVM) Run() error {
 	if vm.Running() {
 		return vm.Error("vm already running")
 	}
 
-	if !vm.preprocessed && !vm.skipPreprocess {
-		preprocess := vm.Preprocess()
-		if preprocess != nil {
-			return preprocess
-		}
-		vm.preprocessed = true
+	if err := vm.load(); err != nil {
+		return err
 	}
 
 	vm.running = true
 	for vm.Running() {
 		if vm.Suspended() {
+			if err := vm.cancelled(); err != nil {
+				vm.Stop()
+				return err
+			}
 			time.Sleep(time.Millisecond * 1)
 			continue
 		}
@@ -106,12 +167,95 @@ func (vm *AutoItVM) Run() error {
 		}
 		if step != nil {
 			vm.Stop()
+			if vm.parentScope == nil && errors.Is(step, errScriptExit) {
+				return nil
+			}
 			return step
 		}
 	}
 	return nil
 }
+//load preprocesses the script once, defining its functions
+func (vm *AutoItVM) load() error {
+	if !vm.preprocessed && !vm.skipPreprocess {
+		preprocess := vm.Preprocess()
+		if preprocess != nil {
+			return preprocess
+		}
+		vm.preprocessed = true
+	}
+	return nil
+}
+
+//Call calls a function defined by the script, or a built-in function, with the given arguments and returns its
+//return value along with the @error and @extended it set. The script is preprocessed first if it hasn't been, but
+//its global variables are only declared once it has been run with Run.
+func (vm *AutoItVM) Call(name string, args ...*Token) (value *Token, errorCode int, extended int, err error) {
+	if err := vm.load(); err != nil {
+		return nil, 0, 0, err
+	}
+
+	functionCall := &FunctionCall{Name: name, Block: make([][]*Token, len(args))}
+	for i, arg := range args {
+		if arg == nil {
+			arg = NewToken(tNULL, "Null")
+		}
+		functionCall.Block[i] = []*Token{arg}
+	}
+	value, err = vm.HandleCall(functionCall)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return value, vm.GetError(), vm.GetExtended(), nil
+}
+
+//Eval evaluates an expression in the global scope of the VM and returns its value
+func (vm *AutoItVM) Eval(expression string) (*Token, error) {
+	global := vm
+	for global.parentScope != nil {
+		global = global.parentScope
+	}
+	if err := global.load(); err != nil {
+		return nil, err
+	}
+
+	lexer := NewLexer([]byte(expression + "\n"))
+	tokens, err := lexer.GetTokens()
+	if err != nil {
+		return nil, err
+	}
+	//Line breaks around the expression aren't part of it
+	for len(tokens) > 0 && tokens[0].Type == tEOL {
+		tokens = tokens[1:]
+	}
+	for len(tokens) > 0 && tokens[len(tokens)-1].Type == tEOL {
+		tokens = tokens[:len(tokens)-1]
+	}
+	if len(tokens) == 0 {
+		return nil, global.Error("eval: empty expression")
+	}
+	tokens = append(tokens, NewToken(tEOL, ""))
+
+	vmEval, err := global.ExtendVM(tokens, true)
+	if err != nil {
+		return nil, err
+	}
+	value, read, err := NewEvaluator(vmEval, vmEval.tokens).Eval(true)
+	if err != nil {
+		return nil, err
+	}
+	if read < len(vmEval.tokens) && vmEval.tokens[read].Type != tEOL {
+		return nil, global.Error("eval: unexpected %v after expression", *vmEval.tokens[read])
+	}
+	global.SetError(vmEval.GetError())
+	global.SetExtended(vmEval.GetExtended())
+	return value, nil
+}
+
 func (vm *AutoItVM) Step() error {
+	if err := vm.cancelled(); err != nil {
+		return err
+	}
 	token := vm.ReadToken()
 	if token == nil {
 		return io.EOF
@@ -119,6 +263,12 @@ func (vm *AutoItVM) Step() error {
 	if token.Type == tEOL || token.Type == tCOMMENT {
 		return nil
 	}
+	if err := vm.countStatement(); err != nil {
+		return err
+	}
+	if err := vm.checkHandles(); err != nil {
+		return err
+	}
 	vm.Log("step: %v", *token)
 
 	switch token.Type {
@@ -134,6 +284,13 @@ func (vm *AutoItVM) Step() error {
 				return err
 			}
 		}
+		if !vm.Policy.allows(CapExit) {
+			if vm.Policy.Fatal {
+				return vm.Error("Exit %w", ErrNotAllowed)
+			}
+			//The script ends without exiting the process
+			return errScriptExit
+		}
 		if vm.exitMethod == "" {
 			os.Exit(vm.exitCode)
 		}
@@ -408,16 +565,64 @@ func (vm *AutoItVM) GetHandle(handleId string) interface{} {
 func (vm *AutoItVM) AddHandle(value interface{}) *Token {
 	handleId := uuid.NewString()
 	vm.handles[handleId] = value
+	if countsAsHandle(value) {
+		vm.usage.handles++
+	}
 	return NewToken(tHANDLE, handleId)
 }
 //DestroyHandle destroys the given handle id
 func (vm *AutoItVM) DestroyHandle(handleId string) {
+	if value, exists := vm.handles[handleId]; exists {
+		if countsAsHandle(value) {
+			vm.usage.handles--
+		}
+	}
 	delete(vm.handles, handleId)
 }
 
+//MapGet returns the value stored at the given key of a map or index of an array, or nil if it's out of range
 func (vm *AutoItVM) MapGet(handleId, key string) *Token {
-	return vm.handles[handleId].(map[string]*Token)[key]
+	switch handle := vm.handles[handleId].(type) {
+	case map[string]*Token:
+		return handle[key]
+	case []*Token:
+		index, err := strconv.Atoi(key)
+		if err != nil || index < 0 || index >= len(handle) {
+			return nil
+		}
+		if handle[index] == nil {
+			return NewToken(tSTRING, "")
+		}
+		return handle[index]
+	}
+	return nil
 }
+//MapSet stores the value at the given key of a map or index of an array
 func (vm *AutoItVM) MapSet(handleId, key string, value *Token) {
-	vm.handles[handleId].(map[string]*Token)[key] = value
+	switch handle := vm.handles[handleId].(type) {
+	case map[string]*Token:
+		handle[key] = value
+	case []*Token:
+		index, err := strconv.Atoi(key)
+		if err != nil || index < 0 || index >= len(handle) {
+			return
+		}
+		handle[index] = value
+	}
+}
+
+//NewArray creates an array handle holding the given values and returns the handle as a token
+func (vm *AutoItVM) NewArray(values []*Token) *Token {
+	return vm.AddHandle(values)
+}
+//GetArray returns the values held by the given array handle or nil if it isn't an array
+func (vm *AutoItVM) GetArray(handle *Token) []*Token {
+	if handle == nil {
+		return nil
+	}
+	values, ok := vm.GetHandle(handle.Handle()).([]*Token)
+	if !ok {
+		return nil
+	}
+	return values
 }
\ No newline at end of file
@@ -67,6 +67,8 @@ func (t *Token) String() string {
 	switch t.Type {
 	case tBINARY:
 		return "0x"+t.Data
+	case tPTR, tHWND:
+		return fmt.Sprintf("0x%0*X", strconv.IntSize/4, t.Uint())
 	/*case tHANDLE:
 		return ""*/
 	}
@@ -109,6 +111,10 @@ func (t *Token) Float64() float64 {
 	if t.IsEmpty() {
 		return 0
 	}
+	if t.Type == tSTRING {
+		number, _, _ := parseNumber(t.Data)
+		return number
+	}
 	data := strip(t.Data)
 	number, err := strconv.ParseFloat(data, 64)
 	if err != nil {
@@ -141,6 +147,82 @@ func (t *Token) Handle() string {
 	return ""
 }
 
+//parseNumber converts a string to a number the way AutoIt does, reading any
+//leading number and ignoring everything after it, returning 0 if there isn't one
+func parseNumber(str string) (float64, int64, bool) {
+	str = strings.TrimLeft(str, " \t\r\n")
+	sign := ""
+	if len(str) > 0 && (str[0] == '-' || str[0] == '+') {
+		sign = str[:1]
+		str = str[1:]
+	}
+
+	if len(str) > 2 && (str[:2] == "0x" || str[:2] == "0X") {
+		digits := ""
+		for _, r := range str[2:] {
+			if !isBinary(r) || len(digits) == 16 {
+				break
+			}
+			digits += string(r)
+		}
+		number, err := strconv.ParseUint(digits, 16, 64)
+		if err != nil {
+			return 0, 0, true
+		}
+		whole := int64(number)
+		if len(digits) <= 8 {
+			whole = int64(int32(number))
+		}
+		if sign == "-" {
+			whole = -whole
+		}
+		return float64(whole), whole, true
+	}
+
+	read := ""
+	isInt := true
+	readDigits := func() {
+		for len(str) > 0 && str[0] >= '0' && str[0] <= '9' {
+			read += str[:1]
+			str = str[1:]
+		}
+	}
+	readDigits()
+	if len(str) > 1 && str[0] == '.' && str[1] >= '0' && str[1] <= '9' {
+		read += "."
+		str = str[1:]
+		readDigits()
+		isInt = false
+	} else if len(str) > 0 && str[0] == '.' {
+		str = str[1:]
+	}
+	if read == "" {
+		return 0, 0, true
+	}
+	if len(str) > 1 && (str[0] == 'e' || str[0] == 'E') {
+		exponent := str[:1]
+		str = str[1:]
+		if str[0] == '-' || str[0] == '+' {
+			exponent += str[:1]
+			str = str[1:]
+		}
+		if len(str) > 0 && str[0] >= '0' && str[0] <= '9' {
+			read += exponent
+			readDigits()
+			isInt = false
+		}
+	}
+
+	if isInt {
+		whole, err := strconv.ParseInt(sign+read, 10, 64)
+		if err == nil {
+			return float64(whole), whole, true
+		}
+	}
+	number, _ := strconv.ParseFloat(sign+read, 64)
+	return number, int64(number), false
+}
+
 func strip(txt string) string {
 	txt = strings.ReplaceAll(txt, "\r", "")
 	txt = strings.ReplaceAll(txt, "\n", "")
@@ -162,6 +244,8 @@ const (
 	tMACRO TokenType = "MACRO"
 	tCOMMENT TokenType = "COMMENT"
 	tVARIABLE TokenType = "VARIABLE"
+	tPROPERTY TokenType = "PROPERTY" //Stores the name of an object member accessed with dot notation
+	tMETHOD TokenType = "METHOD" //Stores a handle to the FunctionCall of an object method
 	tLEFTPAREN TokenType = "LEFTPAREN"
 	tRIGHTPAREN TokenType = "RIGHTPAREN"
 	tLEFTBRACK TokenType = "LEFTBRACK"
@@ -214,4 +298,6 @@ const (
 	tHANDLE TokenType = "HANDLE" //Stores a string holding a handle id
 	tMAP TokenType = "MAP" //Stores a handle to map[string]*Token
 	tARRAY TokenType = "ARRAY" //Stores a handle to []*Token
+	tPTR TokenType = "Ptr" //Stores a pointer as an unsigned integer
+	tHWND TokenType = "HWnd" //Stores a window handle as an unsigned integer
 )
\ No newline at end of file
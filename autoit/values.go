@@ -0,0 +1,200 @@
+package autoit
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+//Types of the values scripts work with, as held by Token.Type. Arrays, maps, objects and other resources are
+//handles, see AutoItVM.TypeOf for the type a script sees.
+const (
+	TypeString  = tSTRING
+	TypeNumber  = tNUMBER
+	TypeDouble  = tDOUBLE
+	TypeBool    = tBOOLEAN
+	TypeBinary  = tBINARY
+	TypePointer = tPTR
+	TypeHWnd    = tHWND
+	TypeNull    = tNULL
+	TypeDefault = tDEFAULT
+	TypeHandle  = tHANDLE
+)
+
+//NewString returns a String value
+func NewString(value string) *Token {
+	return NewToken(tSTRING, value)
+}
+
+//NewInt returns an integer number value
+func NewInt(value int64) *Token {
+	return NewToken(tNUMBER, value)
+}
+
+//NewDouble returns a floating point number value, which stays a Double even if it's a whole number
+func NewDouble(value float64) *Token {
+	//NewToken stores any float as a plain number
+	return &Token{Type: tDOUBLE, Data: strconv.FormatFloat(value, 'f', -1, 64)}
+}
+
+//NewBool returns a Bool value
+func NewBool(value bool) *Token {
+	return NewToken(tBOOLEAN, value)
+}
+
+//NewBinary returns a Binary value
+func NewBinary(value []byte) *Token {
+	return NewToken(tBINARY, value)
+}
+
+//NewPointer returns a Ptr value
+func NewPointer(value uintptr) *Token {
+	return NewToken(tPTR, fmt.Sprintf("%d", uint64(value)))
+}
+
+//NewHWnd returns a HWnd value
+func NewHWnd(value uintptr) *Token {
+	return NewToken(tHWND, fmt.Sprintf("%d", uint64(value)))
+}
+
+//NewNull returns the Null keyword
+func NewNull() *Token {
+	return NewToken(tNULL, "Null")
+}
+
+//NewDefault returns the Default keyword, which makes built-in functions use the default value of an argument
+func NewDefault() *Token {
+	return NewToken(tDEFAULT, "Default")
+}
+
+//NewDate returns a date in the format used by FileGetTime and the objects of scripts
+func NewDate(value time.Time) *Token {
+	return NewToken(tSTRING, value.Format(dateFormat))
+}
+
+//NewMap stores the given values as a map and returns a handle to it
+func (vm *AutoItVM) NewMap(values map[string]*Token) *Token {
+	if values == nil {
+		values = make(map[string]*Token)
+	}
+	return vm.AddHandle(values)
+}
+
+//TypeOf returns the type of a value as a script sees it, as returned by VarGetType
+func (vm *AutoItVM) TypeOf(t *Token) string {
+	return vm.varType(t)
+}
+
+//AsString returns the value of a String
+func (t *Token) AsString() (string, bool) {
+	if t == nil || t.Type != tSTRING {
+		return "", false
+	}
+	return t.Data, true
+}
+
+//AsInt returns the value of a whole number
+func (t *Token) AsInt() (int64, bool) {
+	if t == nil || (t.Type != tNUMBER && t.Type != tDOUBLE) {
+		return 0, false
+	}
+	number, integer, isInt := parseNumber(t.Data)
+	if !isInt && !isWholeNumber(number) {
+		return 0, false
+	}
+	return integer, true
+}
+
+//AsFloat returns the value of a number
+func (t *Token) AsFloat() (float64, bool) {
+	if t == nil || (t.Type != tNUMBER && t.Type != tDOUBLE) {
+		return 0, false
+	}
+	return t.Float64(), true
+}
+
+//AsBool returns the value of a Bool
+func (t *Token) AsBool() (bool, bool) {
+	if t == nil || t.Type != tBOOLEAN {
+		return false, false
+	}
+	return t.Bool(), true
+}
+
+//AsBinary returns the data of a Binary
+func (t *Token) AsBinary() ([]byte, bool) {
+	if t == nil || t.Type != tBINARY {
+		return nil, false
+	}
+	data := t.Bytes()
+	return data, data != nil
+}
+
+//AsPointer returns the address held by a Ptr or HWnd
+func (t *Token) AsPointer() (uintptr, bool) {
+	if t == nil || (t.Type != tPTR && t.Type != tHWND) {
+		return 0, false
+	}
+	return uintptr(t.Uint()), true
+}
+
+//IsNull returns true if the value is the Null keyword
+func (t *Token) IsNull() bool {
+	return t != nil && t.Type == tNULL
+}
+
+//IsDefault returns true if the value is the Default keyword
+func (t *Token) IsDefault() bool {
+	return t != nil && t.Type == tDEFAULT
+}
+
+//ArrayValue returns the values held by an array
+func (vm *AutoItVM) ArrayValue(t *Token) ([]*Token, bool) {
+	values := vm.GetArray(t)
+	return values, values != nil
+}
+
+//MapValue returns the values held by a map
+func (vm *AutoItVM) MapValue(t *Token) (map[string]*Token, bool) {
+	if t == nil || t.Type != tHANDLE {
+		return nil, false
+	}
+	values, ok := vm.GetHandle(t.Handle()).(map[string]*Token)
+	return values, ok
+}
+
+//ObjectValue returns the object held by a value, see ToGo for the Go value wrapped by a host object
+func (vm *AutoItVM) ObjectValue(t *Token) (Object, bool) {
+	object := vm.getObject(t)
+	return object, object != nil
+}
+
+//FromGo converts a Go value to a value scripts can use. Slices and arrays become arrays, except for []byte which
+//becomes Binary, maps become maps keyed by the string form of their keys, time.Time becomes a date string and any
+//other value that isn't a number, string or bool becomes an object with NewHostObject.
+func (vm *AutoItVM) FromGo(value interface{}) *Token {
+	return vm.fromGo(reflect.ValueOf(value))
+}
+
+//ToGo converts a value to the Go value that represents it best: int64 or float64 for numbers, string, bool, []byte
+//for Binary, uintptr for pointers, []interface{} for arrays, map[string]interface{} for maps, nil for Null, the
+//wrapped value for host objects and the underlying value for any other handle.
+func (vm *AutoItVM) ToGo(t *Token) interface{} {
+	return vm.goValue(t)
+}
+
+//ToGoValue converts a value to the type pointed to by target, the same way arguments are converted for the methods
+//of host objects
+func (vm *AutoItVM) ToGoValue(t *Token, target interface{}) error {
+	ptr := reflect.ValueOf(target)
+	if ptr.Kind() != reflect.Ptr || ptr.IsNil() {
+		return fmt.Errorf("target must be a non-nil pointer, got %T", target)
+	}
+	value, err := vm.toGo(t, ptr.Elem().Type())
+	if err != nil {
+		return err
+	}
+	ptr.Elem().Set(value)
+	return nil
+}